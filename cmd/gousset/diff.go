@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pasqal-io/gousset/openapi/diff"
+)
+
+// Returned by `gousset diff` if it finds breaking changes, to fail CI.
+var errBreaking = errors.New("breaking changes detected")
+
+// Compare two specs, e.g. `gousset diff old.yaml new.yaml`.
+func runDiff(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("gousset diff", flag.ContinueOnError)
+	format := flags.String("format", "text", `"text" or "json"`)
+	failOnBreaking := flags.Bool("fail-on-breaking", true, "exit with an error if there are breaking changes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected two specs, the old one and the new one, got %d arguments", flags.NArg())
+	}
+	if *format != "text" && *format != formatJson {
+		return fmt.Errorf(`invalid format "%s", expected "text" or "json"`, *format)
+	}
+	old, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read old spec: %w", err)
+	}
+	new, err := os.ReadFile(flags.Arg(1))
+	if err != nil {
+		return fmt.Errorf("failed to read new spec: %w", err)
+	}
+	report, err := diff.CompareDocuments(old, new)
+	if err != nil {
+		return err
+	}
+	if *format == formatJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, change := range report.Changes {
+			if _, err := fmt.Fprintln(stdout, change.String()); err != nil {
+				return err
+			}
+		}
+	}
+	if *failOnBreaking && report.HasBreaking() {
+		return errBreaking
+	}
+	return nil
+}
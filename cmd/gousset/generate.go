@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	openapiPackage         = "github.com/pasqal-io/gousset/openapi"
+	implementationTypeName = "Implementation"
+)
+
+// How to obtain the `openapi.Implementation` from the target package.
+type source struct {
+	// The import path of the target package.
+	PkgPath string
+
+	// The name of the function or variable.
+	Name string
+
+	// `true` if this is a function, `false` if this is a variable.
+	IsFunc bool
+
+	// `true` if the function also returns an `error`.
+	ReturnsError bool
+}
+
+func isImplementation(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == openapiPackage && obj.Name() == implementationTypeName
+}
+
+// If `obj` is an exported function `func() openapi.Implementation`,
+// `func() (openapi.Implementation, error)` or a variable `openapi.Implementation`,
+// describe how to use it.
+func asSource(pkgPath string, obj types.Object) (source, bool) {
+	if !obj.Exported() {
+		return source{}, false
+	}
+	result := source{
+		PkgPath: pkgPath,
+		Name:    obj.Name(),
+	}
+	switch obj := obj.(type) {
+	case *types.Var:
+		return result, isImplementation(obj.Type())
+	case *types.Func:
+		signature, ok := obj.Type().(*types.Signature)
+		if !ok || signature.Recv() != nil || signature.Params().Len() != 0 || signature.TypeParams().Len() != 0 {
+			return source{}, false
+		}
+		results := signature.Results()
+		result.IsFunc = true
+		switch {
+		case results.Len() == 1 && isImplementation(results.At(0).Type()):
+			return result, true
+		case results.Len() == 2 && isImplementation(results.At(0).Type()) && types.Identical(results.At(1).Type(), types.Universe.Lookup("error").Type()):
+			result.ReturnsError = true
+			return result, true
+		}
+	}
+	return source{}, false
+}
+
+// Find the function or variable providing the `openapi.Implementation` in a package.
+//
+// If `name` is empty, the package must contain exactly one candidate.
+//
+// Returns the source and the directory of the package.
+func findSource(pattern string, name string) (source, string, error) {
+	config := packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+	}
+	pkgs, err := packages.Load(&config, pattern)
+	if err != nil {
+		return source{}, "", fmt.Errorf("failed to load package %s: %w", pattern, err)
+	}
+	if len(pkgs) != 1 {
+		return source{}, "", fmt.Errorf("expected exactly one package matching %s, found %d", pattern, len(pkgs))
+	}
+	pkg := pkgs[0]
+	if len(pkg.Errors) != 0 {
+		return source{}, "", fmt.Errorf("failed to load package %s: %w", pattern, pkg.Errors[0])
+	}
+	if pkg.Name == "main" {
+		return source{}, "", fmt.Errorf("package %s is a main package, which cannot be imported, please move the implementation to another package", pkg.PkgPath)
+	}
+	if len(pkg.GoFiles) == 0 {
+		return source{}, "", fmt.Errorf("package %s contains no Go files", pattern)
+	}
+	dir := filepath.Dir(pkg.GoFiles[0])
+
+	var candidates []source
+	scope := pkg.Types.Scope()
+	for _, objName := range scope.Names() {
+		if name != "" && objName != name {
+			continue
+		}
+		if candidate, ok := asSource(pkg.PkgPath, scope.Lookup(objName)); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	switch len(candidates) {
+	case 1:
+		return candidates[0], dir, nil
+	case 0:
+		if name != "" {
+			return source{}, "", fmt.Errorf("package %s has no exported function or variable %s providing an %s.%s", pkg.PkgPath, name, openapiPackage, implementationTypeName)
+		}
+		return source{}, "", fmt.Errorf("package %s has no exported function or variable providing an %s.%s", pkg.PkgPath, openapiPackage, implementationTypeName)
+	default:
+		names := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			names = append(names, candidate.Name)
+		}
+		return source{}, "", fmt.Errorf("package %s has several candidates (%s), pick one with -symbol", pkg.PkgPath, strings.Join(names, ", "))
+	}
+}
+
+// The program that builds the spec and writes it, as JSON, to stdout.
+var program = template.Must(template.New("main").Parse(`// Code generated by gousset. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pasqal-io/gousset/openapi"
+	target "{{.Source.PkgPath}}"
+)
+
+func main() {
+{{- if .Source.IsFunc}}
+{{- if .Source.ReturnsError}}
+	implem, err := target.{{.Source.Name}}()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to obtain implementation:", err)
+		os.Exit(1)
+	}
+{{- else}}
+	implem := target.{{.Source.Name}}()
+{{- end}}
+{{- else}}
+	implem := target.{{.Source.Name}}
+{{- end}}
+{{- if .OpenApiVersion}}
+	implem.OpenApiVersion = {{printf "%q" .OpenApiVersion}}
+{{- end}}
+	spec, err := openapi.FromImplementation(implem)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to build spec:", err)
+		os.Exit(1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(spec); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to serialize spec:", err)
+		os.Exit(1)
+	}
+}
+`))
+
+// Build and run a temporary program that produces the spec, as JSON.
+//
+// The program is written to a temporary directory and mapped into the
+// directory of the target package with `go run -overlay`, so that it may
+// import the package even if it is internal, without writing to the tree.
+// The temporary directory is removed when done, including if gousset is
+// interrupted with SIGINT or SIGTERM.
+func generate(src source, dir string, openapiVersion string) ([]byte, error) {
+	tmp, err := os.MkdirTemp("", "gousset-gen-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	code := bytes.Buffer{}
+	err = program.Execute(&code, struct {
+		Source         source
+		OpenApiVersion string
+	}{
+		Source:         src,
+		OpenApiVersion: openapiVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate program: %w", err)
+	}
+	programPath := filepath.Join(tmp, "main.go")
+	if err := os.WriteFile(programPath, code.Bytes(), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write program: %w", err)
+	}
+	// The package of the program only exists in the overlay.
+	virtualDir := filepath.Join(dir, filepath.Base(tmp))
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {filepath.Join(virtualDir, "main.go"): programPath},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate overlay: %w", err)
+	}
+	overlayPath := filepath.Join(tmp, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write overlay: %w", err)
+	}
+
+	// On interruption, stop the program, then clean up.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stdout := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "go", "run", "-overlay", overlayPath, "."+string(filepath.Separator)+filepath.Base(tmp))
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("interrupted")
+		}
+		return nil, fmt.Errorf("failed to run program: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
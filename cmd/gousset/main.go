@@ -0,0 +1,125 @@
+// Command gousset generates the OpenAPI spec of a Go package.
+//
+// The package must export either a function `func() openapi.Implementation`,
+// a function `func() (openapi.Implementation, error)` or a variable of type
+// `openapi.Implementation`. gousset builds and runs a temporary program that
+// calls it and writes the resulting spec. The program is kept outside of the
+// source tree and removed when done.
+//
+// Usage:
+//
+//	gousset [flags] [package]
+//	gousset diff [flags] old new
+//
+// The package defaults to the current directory, which makes it suitable for
+// `go generate`, e.g.
+//
+//	//go:generate go run github.com/pasqal-io/gousset/cmd/gousset -o openapi.yaml
+//
+// Flags:
+//
+//	-o path         Write the spec to path instead of stdout.
+//	-format format  Either "json" or "yaml". Defaults to the extension of -o or "json".
+//	-openapi version
+//	                The version of OpenAPI to target, 3.0.x or 3.1.x, e.g. "3.1.0".
+//	-symbol name    The function or variable to use, if the package has several.
+//
+// `gousset diff` compares two specs (JSON or YAML) and lists the changes, exiting
+// with an error if any of them may break existing clients.
+//
+// Flags:
+//
+//	-format format  Either "text" or "json".
+//	-fail-on-breaking
+//	                Exit with an error if there are breaking changes. Defaults to true.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pasqal-io/gousset/inner/serialization"
+)
+
+const (
+	formatJson = "json"
+	formatYaml = "yaml"
+)
+
+// The versions of OpenAPI that the library supports.
+var openapiVersionRegex = regexp.MustCompile(`^3\.[01]\.[0-9]+$`)
+
+// The format to use for a destination, if unspecified.
+func defaultFormat(output string) string {
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".yaml", ".yml":
+		return formatYaml
+	default:
+		return formatJson
+	}
+}
+
+func run(args []string) error {
+	if len(args) != 0 && args[0] == "diff" {
+		return runDiff(args[1:], os.Stdout)
+	}
+	flags := flag.NewFlagSet("gousset", flag.ContinueOnError)
+	output := flags.String("o", "", "write the spec to this file instead of stdout")
+	format := flags.String("format", "", `"json" or "yaml", defaults to the extension of -o or "json"`)
+	openapiVersion := flags.String("openapi", "", `the version of OpenAPI to target, e.g. "3.1.0"`)
+	symbol := flags.String("symbol", "", "the function or variable providing the implementation, if the package has several")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	pattern := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		pattern = flags.Arg(0)
+	default:
+		return fmt.Errorf("expected at most one package, got %d", flags.NArg())
+	}
+	if *format == "" {
+		*format = defaultFormat(*output)
+	}
+	if *format != formatJson && *format != formatYaml {
+		return fmt.Errorf(`invalid format "%s", expected "json" or "yaml"`, *format)
+	}
+	if *openapiVersion != "" && !openapiVersionRegex.MatchString(*openapiVersion) {
+		return fmt.Errorf(`unsupported OpenAPI version "%s", expected 3.0.x or 3.1.x`, *openapiVersion)
+	}
+
+	src, dir, err := findSource(pattern, *symbol)
+	if err != nil {
+		return err
+	}
+	document, err := generate(src, dir, *openapiVersion)
+	if err != nil {
+		return err
+	}
+	if *format == formatYaml {
+		document, err = serialization.JSONToYAML(document)
+		if err != nil {
+			return fmt.Errorf("failed to convert spec to YAML: %w", err)
+		}
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(document)
+		return err
+	}
+	if err := os.WriteFile(*output, document, 0o644); err != nil {
+		return fmt.Errorf("failed to write spec: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "gousset:", err)
+		os.Exit(1)
+	}
+}
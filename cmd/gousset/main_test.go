@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
+)
+
+// Test that we can generate the spec of a package.
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "openapi.json")
+	err := run([]string{"-o", output, "-openapi", "3.1.0", "./testdata/petstore"})
+	assert.NilError(t, err)
+
+	content, err := os.ReadFile(output)
+	assert.NilError(t, err)
+	var spec struct {
+		OpenApi string `json:"openapi"`
+		Info    struct {
+			Title string `json:"title"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationId string `json:"operationId"`
+		} `json:"paths"`
+	}
+	assert.NilError(t, json.Unmarshal(content, &spec))
+	assert.Equal(t, spec.OpenApi, "3.1.0")
+	assert.Equal(t, spec.Info.Title, "Pet store")
+	assert.Equal(t, spec.Paths["/pets/{id}"]["get"].OperationId, "get /pets/:id")
+
+	// The temporary program is never written to the tree.
+	entries, err := os.ReadDir("./testdata/petstore")
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+}
+
+func TestRunErrors(t *testing.T) {
+	err := run([]string{"-format", "xml", "./testdata/petstore"})
+	assert.ErrorContains(t, err, `invalid format "xml"`)
+
+	err = run([]string{"-symbol", "Missing", "./testdata/petstore"})
+	assert.ErrorContains(t, err, "no exported function or variable Missing")
+
+	for _, version := range []string{"2.0", "3.2.0", "3.1", "v3.1.0", "3.1.0-rc0"} {
+		err = run([]string{"-openapi", version, "./testdata/petstore"})
+		assert.ErrorContains(t, err, fmt.Sprintf(`unsupported OpenAPI version "%s"`, version))
+	}
+}
+
+// Test that `gousset diff` reports changes and fails on breaking changes.
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.yaml")
+	new := filepath.Join(dir, "new.json")
+	assert.NilError(t, os.WriteFile(old, []byte(`
+paths:
+  /pets:
+    get: {}
+    post: {}
+`), 0o600))
+	assert.NilError(t, os.WriteFile(new, []byte(`{"paths": {"/pets": {"get": {}}}}`), 0o600))
+
+	stdout := bytes.Buffer{}
+	err := runDiff([]string{old, new}, &stdout)
+	assert.Assert(t, errors.Is(err, errBreaking))
+	assert.Equal(t, stdout.String(), "BREAKING: operation-removed at /paths/~1pets/post: operation post was removed\n")
+
+	stdout.Reset()
+	err = runDiff([]string{"-format", "json", "-fail-on-breaking=false", new, old}, &stdout)
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, json.RawMessage(stdout.Bytes()), `{
+		"changes": [
+			{
+				"kind": "operation-added",
+				"breaking": false,
+				"location": "/paths/~1pets/post",
+				"message": "operation post was added"
+			}
+		]
+	}`)
+}
@@ -0,0 +1,34 @@
+// A tiny service, used to test the command.
+package petstore
+
+import (
+	"reflect"
+
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/shared/structs"
+)
+
+type PetPath struct {
+	Id string `path:"id" description:"The id of the pet"`
+}
+
+func Implementation() openapi.Implementation {
+	return openapi.Implementation{
+		Info: openapi.Info{
+			Title:   "Pet store",
+			Version: "1.0",
+		},
+		Endpoints: []path.Implementation{
+			{
+				Path: "/pets/:id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input:   reflect.TypeFor[structs.Path[PetPath]](),
+						Summary: "Fetch a pet",
+					},
+				},
+			},
+		},
+	}
+}
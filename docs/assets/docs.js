@@ -0,0 +1,167 @@
+// A minimal, dependency-free renderer for OpenAPI specs.
+(function () {
+  "use strict";
+
+  var VERBS = ["get", "put", "post", "delete", "options", "head", "patch", "trace"];
+
+  // Create an element with some text. Text is never interpreted as HTML.
+  function el(tag, text, className) {
+    var node = document.createElement(tag);
+    if (text !== undefined && text !== null) {
+      node.textContent = String(text);
+    }
+    if (className) {
+      node.className = className;
+    }
+    return node;
+  }
+
+  // Follow a local `$ref`, e.g. `#/components/schemas/User`.
+  function resolve(spec, value) {
+    for (var i = 0; i < 32 && value && typeof value.$ref === "string"; i++) {
+      var ref = value.$ref;
+      if (ref.indexOf("#/") !== 0) {
+        break;
+      }
+      var target = spec;
+      var segments = ref.slice(2).split("/");
+      for (var j = 0; j < segments.length && target; j++) {
+        target = target[segments[j].replace(/~1/g, "/").replace(/~0/g, "~")];
+      }
+      if (!target) {
+        break;
+      }
+      value = target;
+    }
+    return value;
+  }
+
+  function typeOf(schema) {
+    if (!schema) {
+      return "";
+    }
+    if (schema.$ref) {
+      return schema.$ref.split("/").pop();
+    }
+    if (schema.type === "array") {
+      return "array of " + typeOf(schema.items);
+    }
+    return [schema.type, schema.format].filter(Boolean).join(" / ");
+  }
+
+  function schemaBlock(schema) {
+    return el("pre", JSON.stringify(schema, null, 2));
+  }
+
+  function parameters(spec, pathItem, operation) {
+    var all = (pathItem.parameters || []).concat(operation.parameters || []);
+    if (all.length === 0) {
+      return null;
+    }
+    var table = el("table");
+    var head = el("tr");
+    ["Name", "In", "Required", "Type", "Description"].forEach(function (title) {
+      head.appendChild(el("th", title));
+    });
+    table.appendChild(head);
+    all.forEach(function (param) {
+      param = resolve(spec, param);
+      var row = el("tr");
+      row.appendChild(el("td", param.name, param.deprecated ? "deprecated" : ""));
+      row.appendChild(el("td", param.in));
+      row.appendChild(el("td", param.required ? "yes" : "no"));
+      row.appendChild(el("td", typeOf(param.schema)));
+      row.appendChild(el("td", param.description || ""));
+      table.appendChild(row);
+    });
+    return table;
+  }
+
+  function content(container, value) {
+    Object.keys(value || {}).forEach(function (mediaType) {
+      container.appendChild(el("h5", mediaType));
+      if (value[mediaType].schema) {
+        container.appendChild(schemaBlock(value[mediaType].schema));
+      }
+      if (value[mediaType].example !== undefined) {
+        container.appendChild(el("h6", "Example"));
+        container.appendChild(schemaBlock(value[mediaType].example));
+      }
+    });
+  }
+
+  function operationBlock(spec, route, verb, pathItem, operation) {
+    var details = el("details", null, "operation");
+    var summary = el("summary");
+    summary.appendChild(el("span", verb, "verb " + verb));
+    summary.appendChild(el("span", route, operation.deprecated ? "deprecated" : ""));
+    if (operation.summary) {
+      summary.appendChild(document.createTextNode(" — " + operation.summary));
+    }
+    details.appendChild(summary);
+    if (operation.description) {
+      details.appendChild(el("p", operation.description));
+    }
+    var params = parameters(spec, pathItem, operation);
+    if (params) {
+      details.appendChild(el("h4", "Parameters"));
+      details.appendChild(params);
+    }
+    var body = resolve(spec, operation.requestBody);
+    if (body) {
+      details.appendChild(el("h4", body.required ? "Request body (required)" : "Request body"));
+      if (body.description) {
+        details.appendChild(el("p", body.description));
+      }
+      content(details, body.content);
+    }
+    var responses = operation.responses || {};
+    Object.keys(responses).forEach(function (code) {
+      var response = resolve(spec, responses[code]);
+      details.appendChild(el("h4", "Response " + code));
+      if (response.description) {
+        details.appendChild(el("p", response.description));
+      }
+      content(details, response.content);
+    });
+    return details;
+  }
+
+  function render(root, spec) {
+    var info = spec.info || {};
+    if (info.title) {
+      document.title = info.title;
+      document.getElementById("title").textContent = info.title;
+    }
+    if (info.version) {
+      root.appendChild(el("p", "Version " + info.version));
+    }
+    if (info.description) {
+      root.appendChild(el("p", info.description));
+    }
+    var paths = spec.paths || {};
+    Object.keys(paths).sort().forEach(function (route) {
+      var pathItem = paths[route];
+      VERBS.forEach(function (verb) {
+        if (pathItem[verb]) {
+          root.appendChild(operationBlock(spec, route, verb, pathItem, pathItem[verb]));
+        }
+      });
+    });
+  }
+
+  var root = document.getElementById("docs");
+  fetch(root.getAttribute("data-spec"), { headers: { Accept: "application/json" } })
+    .then(function (response) {
+      if (!response.ok) {
+        throw new Error("HTTP " + response.status);
+      }
+      return response.json();
+    })
+    .then(function (spec) {
+      render(root, spec);
+    })
+    .catch(function (err) {
+      root.appendChild(el("p", "Failed to load the spec: " + err.message, "error"));
+    });
+})();
@@ -0,0 +1,195 @@
+// Serve an OpenAPI spec and a documentation page over net/http.
+//
+// Mount the handler under a prefix, e.g.
+//
+//	handler, err := docs.NewHandler(spec, docs.Options{Prefix: "/docs"})
+//	if err != nil {
+//		return err
+//	}
+//	mux.Handle("/docs/", handler)
+//
+// to serve
+//
+//   - `/docs/` a documentation page, without any external dependency;
+//   - `/docs/openapi.json` the spec, as JSON;
+//   - `/docs/openapi.yaml` the spec, as YAML;
+//   - `/docs/openapi` the spec, as JSON or YAML depending on header `Accept`.
+//
+// Responses carry an `ETag` and a `Last-Modified` header, so that clients
+// may cache them.
+package docs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pasqal-io/gousset/inner/serialization"
+	"github.com/pasqal-io/gousset/openapi"
+)
+
+//go:embed assets
+var assets embed.FS
+
+var indexTemplate = template.Must(template.ParseFS(assets, "assets/index.html"))
+
+const (
+	contentTypeJson = "application/json"
+	contentTypeYaml = "application/yaml"
+	contentTypeHtml = "text/html; charset=utf-8"
+)
+
+// Options for NewHandler.
+type Options struct {
+	// The path under which the handler is mounted, e.g. "/docs".
+	// If unspecified, the handler is mounted at the root.
+	Prefix string `exhaustruct:"optional"`
+
+	// The date of the spec, for header `Last-Modified`.
+	// If unspecified, the date at which the handler is created.
+	LastModified time.Time `exhaustruct:"optional"`
+}
+
+// A document served by the handler.
+type document struct {
+	contentType string
+	content     []byte
+	etag        string
+}
+
+func makeDocument(contentType string, content []byte) document {
+	hash := sha256.Sum256(content)
+	return document{
+		contentType: contentType,
+		content:     content,
+		etag:        strconv.Quote(hex.EncodeToString(hash[:16])),
+	}
+}
+
+// An http.Handler serving a spec and its documentation.
+type Handler struct {
+	prefix       string
+	lastModified time.Time
+
+	// Documents, indexed by path relative to the prefix, e.g. "/openapi.json".
+	documents map[string]document
+}
+
+// Create a handler serving a spec and its documentation.
+func NewHandler(spec openapi.Spec, options Options) (*Handler, error) {
+	result := &Handler{
+		prefix:       strings.TrimSuffix(options.Prefix, "/"),
+		lastModified: options.LastModified,
+		documents:    make(map[string]document),
+	}
+	if result.lastModified.IsZero() {
+		result.lastModified = time.Now()
+	}
+
+	specJson, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("while serving spec, failed to serialize spec as JSON: %w", err)
+	}
+	specYaml, err := serialization.JSONToYAML(specJson)
+	if err != nil {
+		return nil, fmt.Errorf("while serving spec, failed to serialize spec as YAML: %w", err)
+	}
+	result.documents["/openapi.json"] = makeDocument(contentTypeJson, specJson)
+	result.documents["/openapi.yaml"] = makeDocument(contentTypeYaml, specYaml)
+
+	index := bytes.Buffer{}
+	title := spec.Info.Title
+	if title == "" {
+		title = "API documentation"
+	}
+	if err := indexTemplate.Execute(&index, struct{ Title string }{Title: title}); err != nil {
+		return nil, fmt.Errorf("while serving spec, failed to render documentation page: %w", err)
+	}
+	result.documents["/"] = makeDocument(contentTypeHtml, index.Bytes())
+
+	err = fs.WalkDir(assets, "assets", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || name == "assets/index.html" {
+			return err
+		}
+		content, err := assets.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		contentType := mime.TypeByExtension(name[strings.LastIndexByte(name, '.'):])
+		result.documents["/"+name] = makeDocument(contentType, content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while serving spec, failed to load assets: %w", err)
+	}
+	return result, nil
+}
+
+// Return `true` if the client prefers YAML to JSON, per header `Accept`.
+func prefersYaml(accept string) bool {
+	bestJson, bestYaml := -1.0, -1.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		switch mediaType {
+		case contentTypeJson:
+			bestJson = max(bestJson, quality)
+		case contentTypeYaml, "application/x-yaml", "text/yaml", "text/x-yaml":
+			bestYaml = max(bestYaml, quality)
+		}
+	}
+	return bestYaml > bestJson
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, h.prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if rest == "" {
+		// Relative links in the documentation page require a trailing slash.
+		http.Redirect(w, r, h.prefix+"/", http.StatusMovedPermanently)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if rest == "/openapi" {
+		w.Header().Add("Vary", "Accept")
+		if prefersYaml(r.Header.Get("Accept")) {
+			rest = "/openapi.yaml"
+		} else {
+			rest = "/openapi.json"
+		}
+	}
+	doc, ok := h.documents[rest]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", doc.contentType)
+	w.Header().Set("ETag", doc.etag)
+	// Handles `If-None-Match`, `If-Modified-Since`, `HEAD` and ranges.
+	http.ServeContent(w, r, "", h.lastModified, bytes.NewReader(doc.content))
+}
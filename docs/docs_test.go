@@ -0,0 +1,115 @@
+package docs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pasqal-io/gousset/docs"
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"gotest.tools/assert"
+)
+
+var lastModified = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func makeHandler(t *testing.T) http.Handler {
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Info: openapi.Info{
+			Title:   "Pets <3",
+			Version: "1.0",
+		},
+		Endpoints: []path.Implementation{
+			{
+				Path: "/pets",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {Summary: "List pets"},
+				},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	handler, err := docs.NewHandler(spec, docs.Options{Prefix: "/docs/", LastModified: lastModified})
+	assert.NilError(t, err)
+	mux := http.NewServeMux()
+	mux.Handle("/docs/", handler)
+	return mux
+}
+
+func get(handler http.Handler, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestServeJSON(t *testing.T) {
+	handler := makeHandler(t)
+	recorder := get(handler, "/docs/openapi.json", nil)
+	assert.Equal(t, recorder.Code, http.StatusOK)
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, recorder.Header().Get("Last-Modified"), "Fri, 01 Mar 2024 12:00:00 GMT")
+	var spec map[string]any
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &spec))
+	assert.Equal(t, spec["openapi"], openapi.OpenApiVersion)
+
+	// Caching.
+	etag := recorder.Header().Get("ETag")
+	assert.Assert(t, etag != "")
+	recorder = get(handler, "/docs/openapi.json", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, recorder.Code, http.StatusNotModified)
+	recorder = get(handler, "/docs/openapi.json", map[string]string{"If-Modified-Since": "Sat, 02 Mar 2024 12:00:00 GMT"})
+	assert.Equal(t, recorder.Code, http.StatusNotModified)
+}
+
+func TestServeYAML(t *testing.T) {
+	handler := makeHandler(t)
+	recorder := get(handler, "/docs/openapi.yaml", nil)
+	assert.Equal(t, recorder.Code, http.StatusOK)
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "application/yaml")
+	assert.Assert(t, strings.HasPrefix(recorder.Body.String(), "openapi: 3.0.1\n"))
+	assert.Assert(t, recorder.Header().Get("ETag") != get(handler, "/docs/openapi.json", nil).Header().Get("ETag"))
+}
+
+func TestContentNegotiation(t *testing.T) {
+	handler := makeHandler(t)
+	for accept, expected := range map[string]string{
+		"":                                  "application/json",
+		"*/*":                               "application/json",
+		"application/yaml":                  "application/yaml",
+		"text/yaml, application/json;q=0.5": "application/yaml",
+		"application/yaml;q=0.2, application/json": "application/json",
+	} {
+		recorder := get(handler, "/docs/openapi", map[string]string{"Accept": accept})
+		assert.Equal(t, recorder.Code, http.StatusOK)
+		assert.Equal(t, recorder.Header().Get("Content-Type"), expected, "Accept: %s", accept)
+		assert.Equal(t, recorder.Header().Get("Vary"), "Accept")
+	}
+}
+
+func TestServeDocumentation(t *testing.T) {
+	handler := makeHandler(t)
+	recorder := get(handler, "/docs/", nil)
+	assert.Equal(t, recorder.Code, http.StatusOK)
+	assert.Equal(t, recorder.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	// The title is escaped.
+	assert.Assert(t, strings.Contains(recorder.Body.String(), "<title>Pets &lt;3</title>"))
+
+	recorder = get(handler, "/docs/assets/docs.js", nil)
+	assert.Equal(t, recorder.Code, http.StatusOK)
+	assert.Assert(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/javascript"))
+
+	recorder = get(handler, "/docs/nothing", nil)
+	assert.Equal(t, recorder.Code, http.StatusNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/docs/openapi.json", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, recorder.Code, http.StatusMethodNotAllowed)
+}
@@ -1 +1,356 @@
+// Extract documentation from the Go sources.
+//
+// gousset builds most of the spec by reflection, which cannot see doc comments.
+// This package parses the sources of your packages to recover them, e.g.
+//
+//	comments, err := extract.Load(extract.Options{}, "./api/...")
+//	if err != nil {
+//		return err
+//	}
+//	spec, err := openapi.FromImplementation(openapi.Implementation{
+//		Comments: comments,
+//		// ...
+//	})
+//
+// Doc comments on types and struct fields then serve as descriptions
+// in schemas, parameters and operations, unless overridden by a tag `description`
+// or a method `Description()`. Similarly, the constants declared for a named type
+// in its package, e.g. `const StatusActive Status = "active"`, serve as its enum,
+// unless overridden by a method `Enum()`. See `Options.EnumsRequireDirective`
+// for types whose constants do not list all their values.
 package extract
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi/doc"
+	"golang.org/x/tools/go/packages"
+)
+
+// Options for Load.
+type Options struct {
+	// The directory in which to resolve patterns. If unspecified, the current directory.
+	Dir string `exhaustruct:"optional"`
+
+	// If `true`, also load the `_test.go` files of the packages.
+	Tests bool `exhaustruct:"optional"`
+
+	// If `true`, the constants of a named type only serve as its enum if the
+	// type is marked with a comment `//gousset:enum`. Use this if constants
+	// do not list all the values of their type, e.g. `const DefaultTimeout Duration = 5`.
+	EnumsRequireDirective bool `exhaustruct:"optional"`
+}
+
+// The doc comments of a set of packages.
+type Comments struct {
+	// Doc comments of named types, indexed by "package/path.TypeName".
+	types map[string]string
+
+	// Doc comments of struct fields, indexed by "package/path.TypeName.FieldName".
+	fields map[string]string
+
+	// Doc comments of constants, indexed by "package/path.ConstName".
+	consts map[string]string
+
+	// Constants of named types, indexed by "package/path.TypeName".
+	constants map[string][]constantDecl
+
+	// Named types marked as enums, indexed by "package/path.TypeName".
+	enums map[string]bool
+
+	// If `true`, only collect the constants of the types of `enums`.
+	enumsRequireDirective bool
+}
+
+// The comment marking a named type as an enum, whose values are its constants,
+// see `Options.EnumsRequireDirective`.
+const enumDirective = "//gousset:enum"
+
+// A constant, with its position for sorting in order of declaration.
+type constantDecl struct {
+	doc.Constant
+	position token.Position
+}
+
+var _ doc.Comments = &Comments{}
+
+// Load the doc comments and constants of the packages matching patterns, e.g. "./...".
+//
+// This type-checks the packages and their dependencies, so it may take a few seconds.
+func Load(options Options, patterns ...string) (*Comments, error) {
+	config := packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:   options.Dir,
+		Tests: options.Tests,
+	}
+	pkgs, err := packages.Load(&config, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("while extracting doc comments, failed to load packages: %w", err)
+	}
+	result := &Comments{
+		types:     make(map[string]string),
+		fields:    make(map[string]string),
+		consts:    make(map[string]string),
+		constants: make(map[string][]constantDecl),
+		enums:     make(map[string]bool),
+
+		enumsRequireDirective: options.EnumsRequireDirective,
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) != 0 {
+			return nil, fmt.Errorf("while extracting doc comments, failed to parse package %s: %w", pkg.PkgPath, pkg.Errors[0])
+		}
+		for _, file := range pkg.Syntax {
+			result.addFile(pkg.PkgPath, file)
+		}
+	}
+	// Once we have all the comments, collect the constants.
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		result.addConstants(pkg, seen)
+	}
+	for _, decls := range result.constants {
+		slices.SortFunc(decls, func(a, b constantDecl) int {
+			return cmp.Or(cmp.Compare(a.position.Filename, b.position.Filename), cmp.Compare(a.position.Offset, b.position.Offset))
+		})
+	}
+	return result, nil
+}
+
+// Collect the constants declared in a package for the named types of this package.
+//
+// With `Tests`, a package may be loaded several times, so `seen` records the
+// constants already collected.
+func (c *Comments) addConstants(pkg *packages.Package, seen map[string]bool) {
+	if pkg.Types == nil {
+		return
+	}
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.Const)
+		if !ok {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.Obj().Pkg() != pkg.Types {
+			// Constants of types of other packages, e.g. `time.Duration`, need not be their values.
+			continue
+		}
+		typeKey := fmt.Sprint(named.Obj().Pkg().Path(), ".", named.Obj().Name())
+		if c.enumsRequireDirective && !c.enums[typeKey] {
+			continue
+		}
+		value, ok := constantValue(obj.Val())
+		if !ok {
+			continue
+		}
+		key := fmt.Sprint(pkg.Types.Path(), ".", name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		decl := constantDecl{
+			Constant: doc.Constant{
+				Name:  name,
+				Value: value,
+			},
+			position: pkg.Fset.Position(obj.Pos()),
+		}
+		if comment, ok := c.consts[key]; ok {
+			decl.Comment = &comment
+		}
+		c.constants[typeKey] = append(c.constants[typeKey], decl)
+	}
+}
+
+// Convert the value of a constant to JSON.
+func constantValue(value constant.Value) (any, bool) {
+	switch value.Kind() {
+	case constant.String:
+		return constant.StringVal(value), true
+	case constant.Bool:
+		return constant.BoolVal(value), true
+	case constant.Int:
+		if i, exact := constant.Int64Val(value); exact {
+			return i, true
+		}
+		if u, exact := constant.Uint64Val(value); exact {
+			return u, true
+		}
+		return nil, false
+	case constant.Float:
+		f, _ := constant.Float64Val(value)
+		return f, true
+	default:
+		return nil, false
+	}
+}
+
+// Whether comments contain a directive, e.g. `//gousset:enum`.
+func hasDirective(directive string, groups ...*ast.CommentGroup) bool {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			if strings.TrimSpace(comment.Text) == directive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Return the text of a comment, or `false` if there is no comment.
+func text(groups ...*ast.CommentGroup) (string, bool) {
+	for _, group := range groups {
+		if text := strings.TrimSpace(group.Text()); text != "" {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (c *Comments) addFile(pkgPath string, file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				key := fmt.Sprint(pkgPath, ".", spec.Name.Name)
+				// A lone declaration `type Foo ...` carries its doc on the GenDecl.
+				if comment, ok := text(spec.Doc, spec.Comment, lone(gen)); ok {
+					c.types[key] = comment
+				}
+				if hasDirective(enumDirective, spec.Doc, spec.Comment, lone(gen)) {
+					c.enums[key] = true
+				}
+				if structType, ok := spec.Type.(*ast.StructType); ok {
+					c.addFields(key, structType)
+				}
+			case *ast.ValueSpec:
+				if gen.Tok != token.CONST {
+					continue
+				}
+				comment, ok := text(spec.Doc, spec.Comment, lone(gen))
+				if !ok {
+					continue
+				}
+				for _, name := range spec.Names {
+					c.consts[fmt.Sprint(pkgPath, ".", name.Name)] = comment
+				}
+			}
+		}
+	}
+}
+
+// The doc of a declaration, if it declares a single item.
+func lone(gen *ast.GenDecl) *ast.CommentGroup {
+	if gen.Lparen.IsValid() {
+		return nil
+	}
+	return gen.Doc
+}
+
+func (c *Comments) addFields(typeKey string, structType *ast.StructType) {
+	for _, field := range structType.Fields.List {
+		comment, ok := text(field.Doc, field.Comment)
+		if !ok {
+			continue
+		}
+		for _, name := range field.Names {
+			c.fields[fmt.Sprint(typeKey, ".", name.Name)] = comment
+		}
+		if len(field.Names) == 0 {
+			// Embedded field, named after its type.
+			if name := embeddedName(field.Type); name != "" {
+				c.fields[fmt.Sprint(typeKey, ".", name)] = comment
+			}
+		}
+	}
+}
+
+// The name of an embedded field, e.g. `Foo` for `*pkg.Foo[T]`.
+func embeddedName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.StarExpr:
+		return embeddedName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(expr.X)
+	case *ast.IndexListExpr:
+		return embeddedName(expr.X)
+	default:
+		return ""
+	}
+}
+
+// The key of a named type, without type arguments, e.g. "package/path.Foo"
+// for `Foo[int]`.
+func typeKey(typ reflect.Type) (string, bool) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	name := typ.Name()
+	if name == "" || typ.PkgPath() == "" {
+		return "", false
+	}
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	return fmt.Sprint(typ.PkgPath(), ".", name), true
+}
+
+// The doc comment of a named type, if any.
+func (c *Comments) TypeComment(typ reflect.Type) (string, bool) {
+	key, ok := typeKey(typ)
+	if !ok {
+		return "", false
+	}
+	comment, ok := c.types[key]
+	return comment, ok
+}
+
+// The doc comment of a field of a struct, if any.
+func (c *Comments) FieldComment(container reflect.Type, field string) (string, bool) {
+	key, ok := typeKey(container)
+	if !ok {
+		return "", false
+	}
+	comment, ok := c.fields[fmt.Sprint(key, ".", field)]
+	return comment, ok
+}
+
+// The constants of a named type marked with `//gousset:enum`, in order of declaration.
+func (c *Comments) Constants(typ reflect.Type) []doc.Constant {
+	key, ok := typeKey(typ)
+	if !ok {
+		return nil
+	}
+	decls := c.constants[key]
+	result := make([]doc.Constant, 0, len(decls))
+	for _, decl := range decls {
+		result = append(result, decl.Constant)
+	}
+	return result
+}
+
+// The doc comment of a constant declared in a package, if any.
+func (c *Comments) ConstComment(pkgPath string, name string) (string, bool) {
+	comment, ok := c.consts[fmt.Sprint(pkgPath, ".", name)]
+	return comment, ok
+}
@@ -0,0 +1,219 @@
+package extract_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pasqal-io/gousset/extract"
+	"github.com/pasqal-io/gousset/openapi/operation"
+	"github.com/pasqal-io/gousset/openapi/schema"
+	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
+)
+
+// A user of the service.
+type User struct {
+	// The name of the user.
+	Name string `json:"name"`
+
+	Email string `json:"email"` // How to contact the user.
+
+	// Ignored, the tag takes precedence.
+	Age int `json:"age" description:"The age of the user, in years"`
+
+	Role Role `json:"role"`
+}
+
+// The role of a user.
+//
+//gousset:enum
+type Role string
+
+// Someone who may change anything.
+const RoleAdmin Role = "admin"
+
+// The priority of a task.
+type Priority int //gousset:enum
+
+const (
+	// Whenever possible.
+	PriorityLow Priority = iota
+	PriorityMedium
+	// As soon as possible.
+	PriorityHigh
+)
+
+// A number of seconds.
+type Duration int
+
+// Not an exhaustive list of durations, so `Duration` is not marked as an enum.
+const DefaultTimeout Duration = 5
+
+// Not a value of `time.Duration` for other packages.
+const LongTimeout time.Duration = time.Hour
+
+type (
+	// Parameters for fetching a user.
+	FetchUser struct {
+		Path struct {
+			Name string `path:"name"`
+		}
+	}
+)
+
+// Loading type-checks dependencies, so only do it once.
+var load = sync.OnceValues(func() (*extract.Comments, error) {
+	return extract.Load(extract.Options{Tests: true}, ".")
+})
+
+var loadWithDirective = sync.OnceValues(func() (*extract.Comments, error) {
+	return extract.Load(extract.Options{Tests: true, EnumsRequireDirective: true}, ".")
+})
+
+func TestComments(t *testing.T) {
+	comments, err := load()
+	assert.NilError(t, err)
+
+	comment, ok := comments.TypeComment(reflect.TypeFor[User]())
+	assert.Assert(t, ok)
+	assert.Equal(t, comment, "A user of the service.")
+
+	comment, ok = comments.TypeComment(reflect.TypeFor[*FetchUser]())
+	assert.Assert(t, ok)
+	assert.Equal(t, comment, "Parameters for fetching a user.")
+
+	comment, ok = comments.FieldComment(reflect.TypeFor[User](), "Email")
+	assert.Assert(t, ok)
+	assert.Equal(t, comment, "How to contact the user.")
+
+	_, ok = comments.FieldComment(reflect.TypeFor[User](), "Role")
+	assert.Assert(t, !ok)
+
+	comment, ok = comments.ConstComment(reflect.TypeFor[Role]().PkgPath(), "RoleAdmin")
+	assert.Assert(t, ok)
+	assert.Equal(t, comment, "Someone who may change anything.")
+
+	constants := comments.Constants(reflect.TypeFor[Priority]())
+	assert.Equal(t, len(constants), 3)
+	for i, name := range []string{"PriorityLow", "PriorityMedium", "PriorityHigh"} {
+		assert.Equal(t, constants[i].Name, name)
+		assert.Equal(t, constants[i].Value, int64(i))
+	}
+	assert.Equal(t, *constants[0].Comment, "Whenever possible.")
+	assert.Assert(t, constants[1].Comment == nil)
+
+	constants = comments.Constants(reflect.TypeFor[Duration]())
+	assert.Equal(t, len(constants), 1)
+	assert.Equal(t, constants[0].Name, "DefaultTimeout")
+
+	assert.Equal(t, len(comments.Constants(reflect.TypeFor[time.Duration]())), 0)
+}
+
+// Test that, with EnumsRequireDirective, only the constants of marked types are collected.
+func TestEnumsRequireDirective(t *testing.T) {
+	comments, err := loadWithDirective()
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(comments.Constants(reflect.TypeFor[Role]())), 1)
+	assert.Equal(t, len(comments.Constants(reflect.TypeFor[Priority]())), 3)
+	assert.Equal(t, len(comments.Constants(reflect.TypeFor[Duration]())), 0)
+
+	// Types that are not marked as enums are left open.
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[Duration](), Comments: comments})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, result, `{
+		"type": "number",
+		"format": "int32",
+		"description": "A number of seconds."
+	}`)
+}
+
+// Test that doc comments serve as descriptions, after tags.
+func TestCommentsAsDescriptions(t *testing.T) {
+	comments, err := load()
+	assert.NilError(t, err)
+
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[User](), PublicNameKey: "json", Comments: comments})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, result, `{
+		"type": "object",
+		"description": "A user of the service.",
+		"required": ["name", "email", "age", "role"],
+		"properties": {
+			"name": {"type": "string", "description": "The name of the user."},
+			"email": {"type": "string", "description": "How to contact the user."},
+			"age": {"type": "number", "format": "int32", "description": "The age of the user, in years"},
+			"role": {
+				"type": "string",
+				"description": "The role of a user.",
+				"enum": ["admin"],
+				"x-enum-varnames": ["RoleAdmin"],
+				"x-enum-descriptions": ["Someone who may change anything."]
+			}
+		}
+	}`)
+
+	result, err = schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[Priority](), Comments: comments})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, result, `{
+		"type": "number",
+		"format": "int32",
+		"description": "The priority of a task.",
+		"enum": [0, 1, 2],
+		"x-enum-varnames": ["PriorityLow", "PriorityMedium", "PriorityHigh"],
+		"x-enum-descriptions": ["Whenever possible.", "", "As soon as possible."]
+	}`)
+
+	op, err := operation.FromImplementation(operation.Implementation{
+		Input:    reflect.TypeFor[FetchUser](),
+		Verb:     "get",
+		Path:     "/users/:name",
+		Comments: comments,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, *op.Description, "Parameters for fetching a user.")
+
+	// Without comments, types are only described by their methods.
+	result, err = schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[Duration]()})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, result, `{
+		"type": "number",
+		"format": "int32"
+	}`)
+}
+
+// Parameters for deleting a user.
+type DeleteUser struct {
+	Path struct {
+		Name string `path:"name" description:"The name of the user"`
+	}
+}
+
+func (DeleteUser) Description() string {
+	return "Not the description of the operation."
+}
+
+// Test that only the doc comment of an input describes the operation.
+func TestOperationDescription(t *testing.T) {
+	comments, err := load()
+	assert.NilError(t, err)
+
+	op, err := operation.FromImplementation(operation.Implementation{
+		Input:    reflect.TypeFor[DeleteUser](),
+		Verb:     "delete",
+		Path:     "/users/:name",
+		Comments: comments,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, *op.Description, "Parameters for deleting a user.")
+
+	op, err = operation.FromImplementation(operation.Implementation{
+		Input: reflect.TypeFor[DeleteUser](),
+		Verb:  "delete",
+		Path:  "/users/:name",
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, op.Description == nil)
+}
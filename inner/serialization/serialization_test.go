@@ -5,6 +5,7 @@ import (
 
 	"github.com/pasqal-io/gousset/inner/serialization"
 	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
 )
 
 func TestFlattenStructToJSON(t *testing.T) {
@@ -81,3 +82,18 @@ func TestFlattenStructToJSON(t *testing.T) {
 		}
 		}`)
 }
+
+// Test that YAML preserves the order of keys and only quotes when necessary.
+func TestToYaml(t *testing.T) {
+	document, err := serialization.JSONToYAML([]byte(`{"openapi": "3.0.1", "info": {"version": "1.0", "title": "Pets"}, "tags": ["a", "b"], "components": {}}`))
+	assert.NilError(t, err)
+	assert.Equal(t, string(document), `openapi: 3.0.1
+info:
+  version: "1.0"
+  title: Pets
+tags:
+  - a
+  - b
+components: {}
+`)
+}
@@ -0,0 +1,36 @@
+package serialization
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Convert a JSON document to YAML, preserving the order of keys.
+func JSONToYAML(document []byte) ([]byte, error) {
+	// JSON is a subset of YAML, so this parses the document as is...
+	var node yaml.Node
+	if err := yaml.Unmarshal(document, &node); err != nil {
+		return nil, err
+	}
+	// ...but we prefer the default YAML style for output, i.e. block collections
+	// and strings quoted only when necessary.
+	var restyle func(*yaml.Node)
+	restyle = func(node *yaml.Node) {
+		node.Style = 0
+		for _, child := range node.Content {
+			restyle(child)
+		}
+	}
+	restyle(&node)
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -83,6 +83,12 @@ func Parse(tag reflect.StructTag) (Tags, error) {
 		switch name {
 		case "default":
 			fallthrough
+		case "example":
+			fallthrough
+		case "description":
+			fallthrough
+		case "title":
+			fallthrough
 		case "orMethod":
 			// don't pre-process
 			tags[name] = []string{list}
@@ -252,6 +258,43 @@ func (tags Tags) LookupInt(key string) (*int64, error) {
 	return &found, nil
 }
 
+// Lookup a key whose value is a boolean, as accepted by `strconv.ParseBool`.
+//
+// Returns `nil, nil` if the key is missing and an error if its value
+// does not parse as a boolean.
+func (tags Tags) LookupBool(key string) (*bool, error) {
+	tags.witness.Assert()
+	slice, ok := tags.tags[key]
+	if !ok || len(slice) == 0 {
+		return nil, nil
+	}
+	found, err := strconv.ParseBool(slice[0])
+	if err != nil {
+		return nil, fmt.Errorf("error while attempting to parse tag %s as boolean: %w", key, err)
+	}
+	return &found, nil
+}
+
+// Lookup a key used as a flag, e.g. `deprecated:""`.
+//
+// Returns `false` if the key is missing, `true` if its value is empty,
+// otherwise its value parsed as with `LookupBool`.
+func (tags Tags) LookupFlag(key string) (bool, error) {
+	tags.witness.Assert()
+	slice, ok := tags.tags[key]
+	if !ok {
+		return false, nil
+	}
+	if len(slice) == 0 || slice[0] == "" {
+		return true, nil
+	}
+	found, err := tags.LookupBool(key)
+	if err != nil {
+		return false, err
+	}
+	return *found, nil
+}
+
 // An `example` tag.
 func (tags Tags) Example() *string {
 	return tags.LookupString("example")
@@ -0,0 +1,544 @@
+// `net/http` middleware checking that traffic conforms to an OpenAPI spec
+// extracted by gousset.
+//
+// Use `NewRequestValidator` to reject incoming requests whose parameters
+// or body do not match the spec and `NewResponseValidator` to detect
+// handlers whose responses do not match the spec, so that documentation
+// and behaviour cannot drift apart.
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/media"
+	"github.com/pasqal-io/gousset/openapi/parameter"
+	"github.com/pasqal-io/gousset/openapi/request"
+	"github.com/pasqal-io/gousset/openapi/schema"
+)
+
+// Where a violation was found.
+type In string
+
+const (
+	InPath   = In(parameter.InPath)
+	InQuery  = In(parameter.InQuery)
+	InHeader = In(parameter.InHeader)
+	InCookie = In(parameter.InCookie)
+	InBody   = In("body")
+)
+
+// A single violation of the spec by a request.
+type Violation struct {
+	// Where the violation was found.
+	In In `json:"in"`
+
+	// A JSON pointer to the offending value, starting with the location,
+	// e.g. `/query/page` or `/body/user/name`.
+	Pointer string `json:"pointer"`
+
+	// A human-readable description of the violation.
+	Message string `json:"message"`
+}
+
+// The body of the response sent when a request is rejected.
+type RequestError struct {
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations"`
+}
+
+// Configuration for a RequestValidator.
+type Options struct {
+	// If `true`, reject requests that do not match any route with a 404
+	// and requests that match a route but not a verb with a 405.
+	//
+	// Otherwise, pass them unchecked to the next handler.
+	RejectUnknownRoutes bool `exhaustruct:"optional"`
+
+	// Called when a request is rejected.
+	//
+	// If nil, respond with a 400 and a JSON-encoded RequestError.
+	OnViolations func(w http.ResponseWriter, r *http.Request, violations []Violation) `exhaustruct:"optional"`
+
+	// The maximal size of a body, in bytes. Larger bodies are rejected.
+	//
+	// If 0, `DefaultMaxBodyBytes`. If negative, bodies are not limited.
+	MaxBodyBytes int64 `exhaustruct:"optional"`
+}
+
+// The maximal size of a body, unless specified in `Options`.
+const DefaultMaxBodyBytes = int64(10 << 20)
+
+// Check incoming requests against a spec.
+type RequestValidator struct {
+	router  router
+	options Options
+}
+
+// Create a validator for the requests of a spec.
+func NewRequestValidator(spec openapi.Spec, options Options) (*RequestValidator, error) {
+	router, err := makeRouter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("while compiling request validator, invalid route: %w", err)
+	}
+	return &RequestValidator{
+		router:  router,
+		options: options,
+	}, nil
+}
+
+// Create a validator for the requests of an implementation.
+func NewRequestValidatorFromImplementation(impl openapi.Implementation, options Options) (*RequestValidator, error) {
+	spec, err := openapi.FromImplementation(impl)
+	if err != nil {
+		return nil, fmt.Errorf("while compiling request validator, failed to extract spec: %w", err)
+	}
+	return NewRequestValidator(spec, options)
+}
+
+// Wrap a handler, rejecting requests that do not conform to the spec.
+func (v *RequestValidator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		found, status := v.router.find(r)
+		if status != http.StatusOK {
+			if !v.options.RejectUnknownRoutes {
+				next.ServeHTTP(w, r)
+				return
+			}
+			writeError(w, status, RequestError{
+				Message: http.StatusText(status),
+			})
+			return
+		}
+		violations, err := v.check(w, found, r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, RequestError{
+				Message: err.Error(),
+			})
+			return
+		}
+		if len(violations) != 0 {
+			if v.options.OnViolations != nil {
+				v.options.OnViolations(w, r, violations)
+			} else {
+				writeError(w, http.StatusBadRequest, RequestError{
+					Message:    "invalid request",
+					Violations: violations,
+				})
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Check a request against the spec.
+//
+// The body of the request is consumed and replaced, so that it may
+// be read again by the caller.
+//
+// Returns `nil` if the request doesn't match any route.
+func (v *RequestValidator) Validate(r *http.Request) ([]Violation, error) {
+	found, status := v.router.find(r)
+	if status != http.StatusOK {
+		return nil, nil
+	}
+	return v.check(nil, found, r)
+}
+
+// Check a request that matches a route.
+//
+// `w` is used to close the connection if the body is too large, it may be nil.
+func (v *RequestValidator) check(w http.ResponseWriter, found match, r *http.Request) ([]Violation, error) {
+	var violations []Violation
+	for _, param := range found.parameters() {
+		violations = append(violations, checkParameter(found, param, r)...)
+	}
+	maxBodyBytes := v.options.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	bodyViolations, err := checkBody(w, found, r, maxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append(violations, bodyViolations...), nil
+}
+
+func writeError(w http.ResponseWriter, status int, body RequestError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Extract the raw values for a parameter.
+func rawParameter(found match, param parameter.Spec, r *http.Request) []string {
+	switch param.In {
+	case parameter.InPath:
+		if value, ok := found.pathValues[param.Name]; ok {
+			return []string{value}
+		}
+	case parameter.InQuery:
+		return r.URL.Query()[param.Name]
+	case parameter.InHeader:
+		return r.Header.Values(param.Name)
+	case parameter.InCookie:
+		if cookie, err := r.Cookie(param.Name); err == nil {
+			return []string{cookie.Value}
+		}
+	}
+	return nil
+}
+
+// The style and explode of a parameter, with their defaults.
+func serialization(param parameter.Spec) (parameter.Style, bool) {
+	style := parameter.DefaultStyle(param.In)
+	if param.SchemaSpec != nil && param.SchemaSpec.Style != nil {
+		style = *param.SchemaSpec.Style
+	}
+	explode := parameter.DefaultExplode(style)
+	if param.SchemaSpec != nil && param.SchemaSpec.Explode != nil {
+		explode = *param.SchemaSpec.Explode
+	}
+	return style, explode
+}
+
+// Undo the serialization style of the raw values of a parameter.
+//
+// Arrays are split into their items. With `explode`, styles `form` and `simple`
+// repeat the parameter rather than separating items, so the value is kept whole.
+func unstyle(raw []string, param parameter.Spec) []string {
+	if len(raw) != 1 {
+		return raw
+	}
+	style, explode := serialization(param)
+	isArray := param.SchemaSpec != nil && typeOf(param.SchemaSpec.Schema) == schema.TypeArray
+	value := raw[0]
+	separator := ""
+	switch style {
+	case parameter.StyleForm:
+		if !explode {
+			separator = ","
+		}
+	case parameter.StyleSimple:
+		separator = ","
+	case parameter.StyleLabel:
+		value = strings.TrimPrefix(value, ".")
+		separator = ","
+		if explode {
+			separator = "."
+		}
+	case parameter.StyleMatrix:
+		value = strings.TrimPrefix(value, ";"+param.Name+"=")
+		separator = ","
+		if explode {
+			separator = ";" + param.Name + "="
+		}
+	case parameter.StyleSpaceDelimited:
+		separator = " "
+	case parameter.StylePipeDelimited:
+		separator = "|"
+	}
+	if isArray && separator != "" {
+		return strings.Split(value, separator)
+	}
+	return []string{value}
+}
+
+// Extract the properties of an object serialized as `name[key]=value`.
+func deepObject(param parameter.Spec, r *http.Request) map[string][]string {
+	result := make(map[string][]string)
+	for key, values := range r.URL.Query() {
+		rest, ok := strings.CutPrefix(key, param.Name+"[")
+		if !ok {
+			continue
+		}
+		if property, ok := strings.CutSuffix(rest, "]"); ok {
+			result[property] = values
+		}
+	}
+	return result
+}
+
+func checkParameter(found match, param parameter.Spec, r *http.Request) []Violation {
+	pointer := appendPointer("/"+string(param.In), param.Name)
+	if param.ContentSpec != nil {
+		return checkContentParameter(found, param, r)
+	}
+	if style, _ := serialization(param); style == parameter.StyleDeepObject {
+		return checkDeepObject(found, param, r)
+	}
+	raw := unstyle(rawParameter(found, param, r), param)
+	if len(raw) == 0 {
+		if param.Required {
+			return []Violation{{
+				In:      In(param.In),
+				Pointer: pointer,
+				Message: fmt.Sprintf("missing required %s parameter \"%s\"", param.In, param.Name),
+			}}
+		}
+		return nil
+	}
+	if param.SchemaSpec == nil {
+		return nil
+	}
+	value, err := coerce(raw, param.SchemaSpec.Schema)
+	if err != nil {
+		return []Violation{{
+			In:      In(param.In),
+			Pointer: pointer,
+			Message: err.Error(),
+		}}
+	}
+	return fromSchemaErrors(In(param.In), pointer, schema.ValidateWithDefinitions(param.SchemaSpec.Schema, value, found.definitions))
+}
+
+func checkDeepObject(found match, param parameter.Spec, r *http.Request) []Violation {
+	pointer := appendPointer("/"+string(param.In), param.Name)
+	raw := deepObject(param, r)
+	if len(raw) == 0 {
+		if param.Required {
+			return []Violation{{
+				In:      In(param.In),
+				Pointer: pointer,
+				Message: fmt.Sprintf("missing required %s parameter \"%s\"", param.In, param.Name),
+			}}
+		}
+		return nil
+	}
+	object, _ := param.SchemaSpec.Schema.(schema.Object)
+	value := make(map[string]any)
+	var violations []Violation
+	for _, property := range slices.Sorted(maps.Keys(raw)) {
+		values := raw[property]
+		propertySchema, ok := object.Properties[property]
+		if !ok && object.AdditionalProperties != nil {
+			propertySchema, ok = *object.AdditionalProperties, true
+		}
+		if !ok {
+			// Let the schema report unknown properties.
+			value[property] = values[0]
+			continue
+		}
+		coerced, err := coerce(values, propertySchema)
+		if err != nil {
+			violations = append(violations, Violation{
+				In:      In(param.In),
+				Pointer: appendPointer(pointer, property),
+				Message: err.Error(),
+			})
+			continue
+		}
+		value[property] = coerced
+	}
+	if len(violations) != 0 {
+		return violations
+	}
+	return fromSchemaErrors(In(param.In), pointer, schema.ValidateWithDefinitions(param.SchemaSpec.Schema, value, found.definitions))
+}
+
+// Check a parameter serialized with a media type, e.g. a JSON-encoded object.
+func checkContentParameter(found match, param parameter.Spec, r *http.Request) []Violation {
+	pointer := appendPointer("/"+string(param.In), param.Name)
+	raw := rawParameter(found, param, r)
+	if len(raw) == 0 {
+		if param.Required {
+			return []Violation{{
+				In:      In(param.In),
+				Pointer: pointer,
+				Message: fmt.Sprintf("missing required %s parameter \"%s\"", param.In, param.Name),
+			}}
+		}
+		return nil
+	}
+	for contentType, mediaType := range param.ContentSpec.Content {
+		// The spec mandates a single media type.
+		if mediaType.Schema == nil {
+			return nil
+		}
+		if !isJSON(contentType) {
+			// Other media types are validated as plain strings.
+			return fromSchemaErrors(In(param.In), pointer, schema.ValidateWithDefinitions(*mediaType.Schema, raw[0], found.definitions))
+		}
+		var value any
+		if err := json.Unmarshal([]byte(raw[0]), &value); err != nil {
+			return []Violation{{
+				In:      In(param.In),
+				Pointer: pointer,
+				Message: fmt.Sprintf("invalid JSON: %s", err),
+			}}
+		}
+		return fromSchemaErrors(In(param.In), pointer, schema.ValidateWithDefinitions(*mediaType.Schema, value, found.definitions))
+	}
+	return nil
+}
+
+// The JSON type declared by a schema, if any.
+func typeOf(s schema.Schema) schema.Type {
+	switch typed := s.(type) {
+	case schema.Primitive:
+		return typed.Type
+	case schema.Object:
+		return typed.Type
+	case schema.Array:
+		return typed.Type
+	}
+	return ""
+}
+
+// Convert the raw string values of a parameter into a JSON value, following the schema.
+func coerce(raw []string, s schema.Schema) (any, error) {
+	switch typeOf(s) {
+	case schema.TypeArray:
+		result := make([]any, 0, len(raw))
+		itemSchema := s.(schema.Array).Items
+		for i, item := range raw {
+			value, err := coerce([]string{item}, itemSchema)
+			if err != nil {
+				return nil, fmt.Errorf("in item %d: %w", i, err)
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	case schema.TypeNumber:
+		value, err := strconv.ParseFloat(raw[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got \"%s\"", raw[0])
+		}
+		return value, nil
+	case schema.TypeBool:
+		value, err := strconv.ParseBool(raw[0])
+		if err != nil {
+			return nil, fmt.Errorf("expected a boolean, got \"%s\"", raw[0])
+		}
+		return value, nil
+	case schema.TypeObject:
+		var value any
+		if err := json.Unmarshal([]byte(raw[0]), &value); err != nil {
+			return nil, fmt.Errorf("expected a JSON object: %w", err)
+		}
+		return value, nil
+	default:
+		return raw[0], nil
+	}
+}
+
+// Find the media type declared for a content type, honoring wildcards.
+func findMediaType(content map[string]media.Type, contentType string) (media.Type, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	if found, ok := content[mediaType]; ok {
+		return found, true
+	}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		if found, ok := content[major+"/*"]; ok {
+			return found, true
+		}
+	}
+	found, ok := content["*/*"]
+	return found, ok
+}
+
+// Return `true` if a content type should be decoded as JSON.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Check the body of a request, reading at most `maxBytes` bytes, unless negative.
+func checkBody(w http.ResponseWriter, found match, r *http.Request, maxBytes int64) ([]Violation, error) {
+	if found.operation.Request == nil {
+		return nil, nil
+	}
+	spec, ok := (*found.operation.Request).(request.Spec)
+	if !ok {
+		// No body expected, or a reference we cannot resolve.
+		return nil, nil
+	}
+	var body []byte
+	if r.Body != nil {
+		reader := r.Body
+		if maxBytes >= 0 {
+			reader = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+		read, err := io.ReadAll(reader)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return []Violation{{
+				In:      InBody,
+				Pointer: "/body",
+				Message: fmt.Sprintf("body exceeds the limit of %d bytes", tooLarge.Limit),
+			}}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body: %w", err)
+		}
+		_ = r.Body.Close()
+		body = read
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if len(body) == 0 {
+		if spec.Required {
+			return []Violation{{
+				In:      InBody,
+				Pointer: "/body",
+				Message: "missing required body",
+			}}, nil
+		}
+		return nil, nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	mediaType, ok := findMediaType(spec.Content, contentType)
+	if !ok {
+		return []Violation{{
+			In:      InBody,
+			Pointer: "/body",
+			Message: fmt.Sprintf("unsupported content type \"%s\"", contentType),
+		}}, nil
+	}
+	if mediaType.Schema == nil || !isJSON(contentType) {
+		return nil, nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []Violation{{
+			In:      InBody,
+			Pointer: "/body",
+			Message: fmt.Sprintf("invalid JSON: %s", err),
+		}}, nil
+	}
+	// Per OpenAPI, `readOnly` properties are not expected in requests, even if required.
+	return fromSchemaErrors(InBody, "/body", schema.ValidateWithDefinitions(schema.Project(*mediaType.Schema, schema.DirectionRequest), value, found.definitions)), nil
+}
+
+func fromSchemaErrors(in In, prefix string, errors []schema.ValidationError) []Violation {
+	var result []Violation
+	for _, err := range errors {
+		result = append(result, Violation{
+			In:      in,
+			Pointer: prefix + err.Pointer,
+			Message: err.Message,
+		})
+	}
+	return result
+}
+
+// Extend a JSON pointer with a segment.
+func appendPointer(pointer string, segment string) string {
+	return fmt.Sprint(pointer, "/", strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+}
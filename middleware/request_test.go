@@ -0,0 +1,329 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pasqal-io/gousset/middleware"
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"gotest.tools/assert"
+)
+
+type UserPath struct {
+	Id int `path:"id" description:"The id of the user"`
+}
+
+type UserQuery struct {
+	Verbose bool `query:"verbose" description:"Return more details" default:"false"`
+}
+
+type UserHeader struct {
+	RequestId string `header:"X-Request-Id" description:"A unique id for this request"`
+}
+
+type UserBody struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+	// Required, but only in responses.
+	CreatedAt string `json:"created_at" readOnly:""`
+}
+
+type UserInput struct {
+	Path   UserPath
+	Query  UserQuery
+	Header UserHeader
+	Body   UserBody
+}
+
+func makeValidator(t *testing.T, options middleware.Options) http.Handler {
+	validator, err := middleware.NewRequestValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/users/:id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Put: {
+						Input: reflect.TypeFor[UserInput](),
+					},
+				},
+			},
+		},
+	}, options)
+	assert.NilError(t, err)
+	return validator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func serve(handler http.Handler, method string, target string, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// A valid request goes through.
+func TestValidRequest(t *testing.T) {
+	handler := makeValidator(t, middleware.Options{})
+	recorder := serve(handler, http.MethodPut, "/users/42?verbose=true", `{"name": "John", "tags": ["a", "b"]}`, map[string]string{
+		"X-Request-Id": "abc",
+	})
+	assert.Equal(t, recorder.Code, http.StatusNoContent)
+}
+
+// An invalid request is rejected, with one violation per error.
+func TestInvalidRequest(t *testing.T) {
+	handler := makeValidator(t, middleware.Options{})
+	recorder := serve(handler, http.MethodPut, "/users/forty-two?verbose=maybe", `{"tags": ["a", 2]}`, nil)
+	assert.Equal(t, recorder.Code, http.StatusBadRequest)
+
+	var result middleware.RequestError
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result, middleware.RequestError{
+		Message: "invalid request",
+		Violations: []middleware.Violation{
+			{In: middleware.InPath, Pointer: "/path/id", Message: "expected a number, got \"forty-two\""},
+			{In: middleware.InQuery, Pointer: "/query/verbose", Message: "expected a boolean, got \"maybe\""},
+			{In: middleware.InHeader, Pointer: "/header/X-Request-Id", Message: "missing required header parameter \"X-Request-Id\""},
+			{In: middleware.InBody, Pointer: "/body", Message: "missing required property \"name\""},
+			{In: middleware.InBody, Pointer: "/body/tags/1", Message: "expected string, got number"},
+		},
+	})
+}
+
+// Unknown routes are passed through or rejected, depending on options.
+func TestUnknownRoutes(t *testing.T) {
+	handler := makeValidator(t, middleware.Options{})
+	assert.Equal(t, serve(handler, http.MethodGet, "/nowhere", "", nil).Code, http.StatusNoContent)
+
+	handler = makeValidator(t, middleware.Options{RejectUnknownRoutes: true})
+	assert.Equal(t, serve(handler, http.MethodGet, "/nowhere", "", nil).Code, http.StatusNotFound)
+	assert.Equal(t, serve(handler, http.MethodGet, "/users/42", "", nil).Code, http.StatusMethodNotAllowed)
+}
+
+// Bodies larger than the limit are rejected without being read in full.
+func TestMaxBodyBytes(t *testing.T) {
+	body := `{"name": "John", "tags": ["a", "b"]}`
+	header := map[string]string{"X-Request-Id": "abc"}
+	handler := makeValidator(t, middleware.Options{MaxBodyBytes: 16})
+	recorder := serve(handler, http.MethodPut, "/users/42", body, header)
+	assert.Equal(t, recorder.Code, http.StatusBadRequest)
+
+	var result middleware.RequestError
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Violations, []middleware.Violation{
+		{In: middleware.InBody, Pointer: "/body", Message: "body exceeds the limit of 16 bytes"},
+	})
+
+	handler = makeValidator(t, middleware.Options{MaxBodyBytes: int64(len(body))})
+	assert.Equal(t, serve(handler, http.MethodPut, "/users/42", body, header).Code, http.StatusNoContent)
+
+	handler = makeValidator(t, middleware.Options{MaxBodyBytes: -1})
+	assert.Equal(t, serve(handler, http.MethodPut, "/users/42", body, header).Code, http.StatusNoContent)
+}
+
+type Page int
+
+func (Page) ParameterComponent() string {
+	return "Page"
+}
+
+type PageQuery struct {
+	Page Page `query:"page" description:"The page" minimum:"1"`
+}
+
+// Parameters documented as components are validated.
+func TestComponentParameter(t *testing.T) {
+	validator, err := middleware.NewRequestValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/users",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[struct{ Query PageQuery }](),
+					},
+				},
+			},
+		},
+	}, middleware.Options{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	assert.Equal(t, serve(handler, http.MethodGet, "/users?page=2", "", nil).Code, http.StatusNoContent)
+
+	recorder := serve(handler, http.MethodGet, "/users?page=0", "", nil)
+	assert.Equal(t, recorder.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(recorder.Body.String(), "/query/page"), recorder.Body.String())
+}
+
+type StyledPath struct {
+	Ids []int `path:"ids" description:"The ids" style:"label" explode:"true"`
+}
+
+type StyledFilter struct {
+	Page int    `query:"page"`
+	Name string `query:"name"`
+}
+
+type StyledQuery struct {
+	Tags   []string     `query:"tags" description:"The tags" style:"pipeDelimited"`
+	Filter StyledFilter `query:"filter" description:"The filter" style:"deepObject"`
+}
+
+// Parameters are decoded according to their style.
+func TestParameterStyles(t *testing.T) {
+	validator, err := middleware.NewRequestValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/items/:ids",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[struct {
+							Path  StyledPath
+							Query StyledQuery
+						}](),
+					},
+				},
+			},
+		},
+	}, middleware.Options{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	recorder := serve(handler, http.MethodGet, "/items/.1.2.3?tags=a|b&filter[page]=2&filter[name]=x", "", nil)
+	assert.Equal(t, recorder.Code, http.StatusNoContent, recorder.Body.String())
+
+	recorder = serve(handler, http.MethodGet, "/items/.1.two?tags=a&filter[page]=two", "", nil)
+	assert.Equal(t, recorder.Code, http.StatusBadRequest)
+	var result middleware.RequestError
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Violations, []middleware.Violation{
+		{In: middleware.InPath, Pointer: "/path/ids", Message: "in item 1: expected a number, got \"two\""},
+		{In: middleware.InQuery, Pointer: "/query/filter/page", Message: "expected a number, got \"two\""},
+	})
+}
+
+type SearchCriteria struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+type SearchQuery struct {
+	Filter SearchCriteria `query:"filter" description:"The filter" content:"application/json"`
+}
+
+// Parameters serialized as JSON are decoded and validated.
+func TestContentParameter(t *testing.T) {
+	validator, err := middleware.NewRequestValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/search",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[struct{ Query SearchQuery }](),
+					},
+				},
+			},
+		},
+	}, middleware.Options{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	filter := url.QueryEscape(`{"name": "x", "limit": 10}`)
+	recorder := serve(handler, http.MethodGet, "/search?filter="+filter, "", nil)
+	assert.Equal(t, recorder.Code, http.StatusNoContent, recorder.Body.String())
+
+	for query, pointer := range map[string]string{
+		"": "/query/filter",
+		"?filter=" + url.QueryEscape(`{"name": "x"`):                "/query/filter",
+		"?filter=" + url.QueryEscape(`{"name": "x", "limit": "a"}`): "/query/filter/limit",
+	} {
+		recorder := serve(handler, http.MethodGet, "/search"+query, "", nil)
+		assert.Equal(t, recorder.Code, http.StatusBadRequest, query)
+		assert.Assert(t, strings.Contains(recorder.Body.String(), pointer), recorder.Body.String())
+	}
+}
+
+type ExplodedQuery struct {
+	Ids []int `query:"ids" description:"Repeated ids"`
+	Csv []int `query:"csv" description:"Comma-separated ids" explode:"false"`
+}
+
+type ExplodedHeader struct {
+	Ids []int `header:"X-Ids" description:"Comma-separated ids"`
+}
+
+// Only styles that separate items with commas are split on commas.
+func TestCommaSeparatedParameters(t *testing.T) {
+	validator, err := middleware.NewRequestValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/items",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[struct {
+							Query  ExplodedQuery
+							Header ExplodedHeader
+						}](),
+					},
+				},
+			},
+		},
+	}, middleware.Options{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	recorder := serve(handler, http.MethodGet, "/items?ids=1&ids=2&csv=3,4", "", map[string]string{"X-Ids": "5,6"})
+	assert.Equal(t, recorder.Code, http.StatusNoContent, recorder.Body.String())
+
+	// With `explode`, style `form` repeats the parameter, so a comma is part of the item.
+	recorder = serve(handler, http.MethodGet, "/items?ids=1,2&csv=3", "", map[string]string{"X-Ids": "5"})
+	assert.Equal(t, recorder.Code, http.StatusBadRequest)
+	var result middleware.RequestError
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Violations, []middleware.Violation{
+		{In: middleware.InQuery, Pointer: "/query/ids", Message: "in item 0: expected a number, got \"1,2\""},
+	})
+}
+
+type PlainQuery struct {
+	Code string `query:"code" description:"A code" content:"text/plain" maxLength:"3"`
+}
+
+// Parameters serialized with other media types are validated as strings.
+func TestPlainContentParameter(t *testing.T) {
+	validator, err := middleware.NewRequestValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/codes",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[struct{ Query PlainQuery }](),
+					},
+				},
+			},
+		},
+	}, middleware.Options{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	assert.Equal(t, serve(handler, http.MethodGet, "/codes?code=abc", "", nil).Code, http.StatusNoContent)
+
+	recorder := serve(handler, http.MethodGet, "/codes?code=abcd", "", nil)
+	assert.Equal(t, recorder.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(recorder.Body.String(), "/query/code"), recorder.Body.String())
+}
@@ -0,0 +1,304 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/header"
+	"github.com/pasqal-io/gousset/openapi/response"
+	"github.com/pasqal-io/gousset/openapi/schema"
+)
+
+const (
+	// A violation of the status codes declared by the spec.
+	InStatus = In("status")
+)
+
+// What to do when a response does not conform to the spec.
+type ResponseMode int
+
+const (
+	// Replace the response with a 500 listing the violations.
+	//
+	// Use this in tests and debug builds.
+	ResponseModeFail = ResponseMode(iota)
+
+	// Send the response unchanged and log the violations.
+	//
+	// Use this in production.
+	ResponseModeLog
+)
+
+// The body of the response sent in ResponseModeFail when a response is rejected.
+type ResponseError struct {
+	Message    string      `json:"message"`
+	Status     int         `json:"status"`
+	Violations []Violation `json:"violations"`
+}
+
+// Configuration for a ResponseValidator.
+type ResponseOptions struct {
+	// What to do when a response does not conform to the spec.
+	Mode ResponseMode `exhaustruct:"optional"`
+
+	// The logger used in ResponseModeLog. If nil, `slog.Default()`.
+	Logger *slog.Logger `exhaustruct:"optional"`
+
+	// If provided, called whenever a response does not conform to the spec,
+	// in addition to the behavior specified by Mode.
+	OnViolations func(r *http.Request, status int, violations []Violation) `exhaustruct:"optional"`
+}
+
+// Check outgoing responses against a spec.
+type ResponseValidator struct {
+	router  router
+	options ResponseOptions
+}
+
+// Create a validator for the responses of a spec.
+func NewResponseValidator(spec openapi.Spec, options ResponseOptions) (*ResponseValidator, error) {
+	router, err := makeRouter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("while compiling response validator, invalid route: %w", err)
+	}
+	return &ResponseValidator{
+		router:  router,
+		options: options,
+	}, nil
+}
+
+// Create a validator for the responses of an implementation.
+func NewResponseValidatorFromImplementation(impl openapi.Implementation, options ResponseOptions) (*ResponseValidator, error) {
+	spec, err := openapi.FromImplementation(impl)
+	if err != nil {
+		return nil, fmt.Errorf("while compiling response validator, failed to extract spec: %w", err)
+	}
+	return NewResponseValidator(spec, options)
+}
+
+// A ResponseWriter that captures the response.
+//
+// If `passthrough` is nil, the response is only buffered, otherwise it
+// is also written to `passthrough`.
+type capture struct {
+	passthrough http.ResponseWriter
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+
+	// `true` if the handler attempted to flush a buffered response.
+	flushed bool
+}
+
+func (c *capture) Header() http.Header {
+	if c.passthrough != nil {
+		return c.passthrough.Header()
+	}
+	return c.header
+}
+
+func (c *capture) WriteHeader(status int) {
+	if c.status != 0 {
+		return
+	}
+	c.status = status
+	if c.passthrough != nil {
+		c.passthrough.WriteHeader(status)
+	}
+}
+
+func (c *capture) Write(data []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.body.Write(data)
+	if c.passthrough != nil {
+		return c.passthrough.Write(data)
+	}
+	return len(data), nil
+}
+
+// Flush the response to `passthrough`.
+//
+// If the response is buffered, it cannot be sent before it is validated,
+// so the attempt is recorded and reported as a violation.
+func (c *capture) Flush() {
+	if c.passthrough == nil {
+		c.flushed = true
+		return
+	}
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(c.passthrough).Flush()
+}
+
+// The underlying ResponseWriter, for use by `http.ResponseController`.
+//
+// Nil if the response is buffered.
+func (c *capture) Unwrap() http.ResponseWriter {
+	return c.passthrough
+}
+
+var _ http.Flusher = &capture{}
+
+// Wrap a handler, checking the responses it sends.
+func (v *ResponseValidator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		found, status := v.router.find(r)
+		if status != http.StatusOK {
+			next.ServeHTTP(w, r)
+			return
+		}
+		captured := &capture{header: make(http.Header)}
+		if v.options.Mode == ResponseModeLog {
+			captured.passthrough = w
+		}
+		next.ServeHTTP(captured, r)
+		if captured.status == 0 {
+			captured.status = http.StatusOK
+		}
+		violations := checkResponse(found, captured.status, captured.Header(), captured.body.Bytes())
+		if captured.flushed {
+			violations = append(violations, Violation{
+				In:      InBody,
+				Pointer: "/body",
+				Message: "the handler flushed the response, which ResponseModeFail buffers until it is validated, use ResponseModeLog to stream responses",
+			})
+		}
+		if len(violations) != 0 && v.options.OnViolations != nil {
+			v.options.OnViolations(r, captured.status, violations)
+		}
+		if v.options.Mode == ResponseModeLog {
+			if len(violations) != 0 {
+				logger := v.options.Logger
+				if logger == nil {
+					logger = slog.Default()
+				}
+				logger.Warn("gousset.middleware.ResponseValidator: response does not conform to the spec",
+					"method", r.Method,
+					"route", found.route,
+					"status", captured.status,
+					"violations", violations)
+			}
+			return
+		}
+		if len(violations) != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(ResponseError{
+				Message:    "response does not conform to the spec",
+				Status:     captured.status,
+				Violations: violations,
+			})
+			return
+		}
+		for k, values := range captured.header {
+			w.Header()[k] = values
+		}
+		w.WriteHeader(captured.status)
+		_, _ = w.Write(captured.body.Bytes())
+	})
+}
+
+// Check a response to a request against the spec.
+//
+// Returns `nil` if the request doesn't match any route.
+func (v *ResponseValidator) Validate(r *http.Request, status int, header http.Header, body []byte) []Violation {
+	found, routeStatus := v.router.find(r)
+	if routeStatus != http.StatusOK {
+		return nil
+	}
+	return checkResponse(found, status, header, body)
+}
+
+// Find the response declared for a status code, falling back to the default response.
+func findResponse(responses response.Responses, status int) (response.Response, bool) {
+	if responses.PerCode != nil {
+		if found, ok := (*responses.PerCode)[uint16(status)]; ok {
+			return found, true
+		}
+	}
+	if responses.Default == nil {
+		return nil, false
+	}
+	return responses.Default, true
+}
+
+func checkResponse(found match, status int, headers http.Header, body []byte) []Violation {
+	resp, ok := findResponse(found.operation.Responses, status)
+	if !ok {
+		return []Violation{{
+			In:      InStatus,
+			Pointer: "/status",
+			Message: fmt.Sprintf("undeclared status code %d", status),
+		}}
+	}
+	spec, ok := resp.(response.Spec)
+	if !ok {
+		// A reference we cannot resolve.
+		return nil
+	}
+	var violations []Violation
+	if spec.Headers != nil {
+		for name, h := range *spec.Headers {
+			headerSpec, ok := h.(header.Spec)
+			if !ok || !headerSpec.Required {
+				continue
+			}
+			if len(headers.Values(name)) == 0 {
+				violations = append(violations, Violation{
+					In:      InHeader,
+					Pointer: appendPointer("/header", name),
+					Message: fmt.Sprintf("missing required header \"%s\"", name),
+				})
+			}
+		}
+	}
+	if len(body) == 0 {
+		return violations
+	}
+	if spec.Content == nil {
+		return append(violations, Violation{
+			In:      InBody,
+			Pointer: "/body",
+			Message: fmt.Sprintf("response %d does not declare any content, but a body was sent", status),
+		})
+	}
+	contentType := headers.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, ok := findMediaType(*spec.Content, contentType)
+	if !ok {
+		declared := []string{}
+		for k := range *spec.Content {
+			declared = append(declared, k)
+		}
+		slices.Sort(declared)
+		return append(violations, Violation{
+			In:      InBody,
+			Pointer: "/body",
+			Message: fmt.Sprintf("undeclared content type \"%s\", expected one of %s", contentType, strings.Join(declared, ", ")),
+		})
+	}
+	if mediaType.Schema == nil || !isJSON(contentType) {
+		return violations
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return append(violations, Violation{
+			In:      InBody,
+			Pointer: "/body",
+			Message: fmt.Sprintf("invalid JSON: %s", err),
+		})
+	}
+	// Per OpenAPI, `writeOnly` properties are not expected in responses, even if required.
+	return append(violations, fromSchemaErrors(InBody, "/body", schema.ValidateWithDefinitions(schema.Project(*mediaType.Schema, schema.DirectionResponse), value, found.definitions))...)
+}
@@ -0,0 +1,204 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pasqal-io/gousset/middleware"
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/media"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/response"
+	"github.com/pasqal-io/gousset/shared/structs"
+	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
+)
+
+type User struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func makeUserSpec(t *testing.T) openapi.Spec {
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/users/:id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[UserPath]](),
+						Response: response.Implementation{
+							PerCode: &map[uint16]response.ResponseImplementation{
+								200: {
+									Description: "The user",
+									Content: &map[string]media.Implementation{
+										"application/json": {
+											Type: reflect.TypeFor[User](),
+										},
+									},
+								},
+								404: {
+									Description: "No such user",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	return spec
+}
+
+func respondWith(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+// A conforming response is sent unchanged.
+func TestValidResponse(t *testing.T) {
+	spec := makeUserSpec(t)
+	validator, err := middleware.NewResponseValidator(spec, middleware.ResponseOptions{})
+	assert.NilError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	recorder := testutils.ServeAndValidateResponse(t, spec, validator.Wrap(respondWith(200, `{"id": 42, "name": "John"}`)), req)
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, recorder.Body.String(), `{"id": 42, "name": "John"}`)
+}
+
+// In ResponseModeFail, a non-conforming response is replaced with a 500.
+func TestInvalidResponseFail(t *testing.T) {
+	validator, err := middleware.NewResponseValidator(makeUserSpec(t), middleware.ResponseOptions{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(respondWith(200, `{"id": "42"}`))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, recorder.Code, http.StatusInternalServerError)
+
+	var result middleware.ResponseError
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result, middleware.ResponseError{
+		Message: "response does not conform to the spec",
+		Status:  200,
+		Violations: []middleware.Violation{
+			{In: middleware.InBody, Pointer: "/body", Message: "missing required property \"name\""},
+			{In: middleware.InBody, Pointer: "/body/id", Message: "expected number, got string"},
+		},
+	})
+
+	handler = validator.Wrap(respondWith(500, `{}`))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Violations, []middleware.Violation{
+		{In: middleware.InStatus, Pointer: "/status", Message: "undeclared status code 500"},
+	})
+
+	handler = validator.Wrap(respondWith(404, `{}`))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Violations, []middleware.Violation{
+		{In: middleware.InBody, Pointer: "/body", Message: "response 404 does not declare any content, but a body was sent"},
+	})
+}
+
+// In ResponseModeLog, a non-conforming response is sent unchanged and reported.
+func TestInvalidResponseLog(t *testing.T) {
+	var reported []middleware.Violation
+	validator, err := middleware.NewResponseValidator(makeUserSpec(t), middleware.ResponseOptions{
+		Mode: middleware.ResponseModeLog,
+		OnViolations: func(r *http.Request, status int, violations []middleware.Violation) {
+			reported = violations
+		},
+	})
+	assert.NilError(t, err)
+	handler := validator.Wrap(respondWith(200, `{"id": 42}`))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, recorder.Body.String(), `{"id": 42}`)
+	assert.DeepEqual(t, reported, []middleware.Violation{
+		{In: middleware.InBody, Pointer: "/body", Message: "missing required property \"name\""},
+	})
+}
+
+func respondWithFlush(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+		w.(http.Flusher).Flush()
+	})
+}
+
+// In ResponseModeLog, flushes reach the underlying ResponseWriter.
+func TestFlushLog(t *testing.T) {
+	validator, err := middleware.NewResponseValidator(makeUserSpec(t), middleware.ResponseOptions{
+		Mode: middleware.ResponseModeLog,
+	})
+	assert.NilError(t, err)
+	handler := validator.Wrap(respondWithFlush(200, `{"id": 42, "name": "John"}`))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, recorder.Code, 200)
+	assert.Assert(t, recorder.Flushed)
+}
+
+// In ResponseModeFail, flushes are reported rather than sent.
+func TestFlushFail(t *testing.T) {
+	validator, err := middleware.NewResponseValidator(makeUserSpec(t), middleware.ResponseOptions{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(respondWithFlush(200, `{"id": 42, "name": "John"}`))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, recorder.Code, http.StatusInternalServerError)
+	var result middleware.ResponseError
+	assert.NilError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Violations, []middleware.Violation{
+		{In: middleware.InBody, Pointer: "/body", Message: "the handler flushed the response, which ResponseModeFail buffers until it is validated, use ResponseModeLog to stream responses"},
+	})
+}
+
+// A default response with an empty description is still declared.
+func TestEmptyDefaultResponse(t *testing.T) {
+	validator, err := middleware.NewResponseValidatorFromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/users/:id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[UserPath]](),
+						Response: response.Implementation{
+							Default: response.ResponseImplementation{
+								Content: &map[string]media.Implementation{
+									"application/json": {
+										Type: reflect.TypeFor[User](),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, middleware.ResponseOptions{})
+	assert.NilError(t, err)
+	handler := validator.Wrap(respondWith(500, `{"id": 42, "name": "John"}`))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	assert.Equal(t, recorder.Code, 500, recorder.Body.String())
+}
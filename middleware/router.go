@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/operation"
+	"github.com/pasqal-io/gousset/openapi/parameter"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/schema"
+)
+
+// A route, compiled for matching against incoming requests.
+type compiledRoute struct {
+	route    path.Route
+	spec     path.Spec
+	regexp   *regexp.Regexp
+	captures []string
+	// The number of template variables, used to give priority to concrete routes.
+	templated int
+	// The number of literal characters, used to give priority to more specific routes.
+	literal int
+}
+
+var templateVariableRegex = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// The path parameters that match the rest of the path, e.g. `{rest...}` in `net/http`.
+func restParameters(spec path.Spec, components map[string]parameter.Parameter) map[string]bool {
+	result := make(map[string]bool)
+	var params []parameter.Parameter
+	if spec.Parameters != nil {
+		params = append(params, *spec.Parameters...)
+	}
+	for _, op := range spec.Operations() {
+		params = append(params, op.Parameters...)
+	}
+	for _, param := range params {
+		if spec, ok := parameter.Resolve(param, components); ok && spec.In == parameter.InPath && spec.Rest {
+			result[spec.Name] = true
+		}
+	}
+	return result
+}
+
+func compileRoute(route path.Route, spec path.Spec, components map[string]parameter.Parameter) (compiledRoute, error) {
+	result := compiledRoute{
+		route: route,
+		spec:  spec,
+	}
+	wildcards := restParameters(spec, components)
+	builder := strings.Builder{}
+	builder.WriteString("^")
+	rest := string(route)
+	for {
+		loc := templateVariableRegex.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+		name := rest[loc[2]:loc[3]]
+		builder.WriteString(regexp.QuoteMeta(rest[:loc[0]]))
+		if wildcards[name] {
+			builder.WriteString("(.+)")
+		} else {
+			builder.WriteString("([^/]+)")
+		}
+		result.literal += loc[0]
+		result.templated++
+		result.captures = append(result.captures, name)
+		rest = rest[loc[1]:]
+	}
+	builder.WriteString(regexp.QuoteMeta(rest))
+	builder.WriteString("$")
+	result.literal += len(rest)
+	compiled, err := regexp.Compile(builder.String())
+	if err != nil {
+		return compiledRoute{}, err
+	}
+	result.regexp = compiled
+	return result, nil
+}
+
+// A table of routes.
+type router struct {
+	routes []compiledRoute
+
+	// The schemas of `components.schemas`, used to resolve references.
+	definitions map[string]schema.Schema
+
+	// The parameters of `components.parameters`, used to resolve references.
+	parameters map[string]parameter.Parameter
+}
+
+func makeRouter(spec openapi.Spec) (router, error) {
+	result := router{}
+	if spec.Components.Schemas != nil {
+		result.definitions = *spec.Components.Schemas
+	}
+	if spec.Components.Parameters != nil {
+		result.parameters = *spec.Components.Parameters
+	}
+	for route, pathSpec := range spec.Paths {
+		compiled, err := compileRoute(route, pathSpec, result.parameters)
+		if err != nil {
+			return router{}, err
+		}
+		result.routes = append(result.routes, compiled)
+	}
+	// Per OpenAPI, concrete paths are matched before their templated counterparts.
+	slices.SortFunc(result.routes, func(a, b compiledRoute) int {
+		if a.templated != b.templated {
+			return a.templated - b.templated
+		}
+		if a.literal != b.literal {
+			return b.literal - a.literal
+		}
+		return strings.Compare(string(a.route), string(b.route))
+	})
+	return result, nil
+}
+
+// An operation matched by a request.
+type match struct {
+	route     path.Route
+	verb      path.Verb
+	pathSpec  path.Spec
+	operation *operation.Spec
+
+	// The schemas of `components.schemas`, used to resolve references.
+	definitions map[string]schema.Schema
+
+	// The parameters of `components.parameters`, used to resolve references.
+	components map[string]parameter.Parameter
+
+	// The values of template variables, unescaped.
+	pathValues map[string]string
+}
+
+// The parameters applicable to the matched operation, including
+// those declared at path level and not overridden by the operation.
+func (m match) parameters() []parameter.Spec {
+	var result []parameter.Spec
+	for _, param := range m.operation.Parameters {
+		if spec, ok := parameter.Resolve(param, m.components); ok {
+			result = append(result, spec)
+		}
+	}
+	if m.pathSpec.Parameters != nil {
+		for _, param := range *m.pathSpec.Parameters {
+			spec, ok := parameter.Resolve(param, m.components)
+			if !ok {
+				continue
+			}
+			overridden := slices.ContainsFunc(result, func(other parameter.Spec) bool {
+				return other.In == spec.In && other.Name == spec.Name
+			})
+			if !overridden {
+				result = append(result, spec)
+			}
+		}
+	}
+	return result
+}
+
+// Find the operation matching a request.
+//
+// Returns `http.StatusOK` and the operation in case of success, `http.StatusNotFound`
+// if no route matches, `http.StatusMethodNotAllowed` if a route matches but does not
+// support the method.
+func (r router) find(req *http.Request) (match, int) {
+	escaped := req.URL.EscapedPath()
+	verb := path.Verb(strings.ToLower(req.Method))
+	status := http.StatusNotFound
+	for _, route := range r.routes {
+		submatches := route.regexp.FindStringSubmatch(escaped)
+		if submatches == nil {
+			continue
+		}
+		op, ok := route.spec.Operations()[verb]
+		if !ok {
+			status = http.StatusMethodNotAllowed
+			continue
+		}
+		values := make(map[string]string)
+		for i, name := range route.captures {
+			value, err := url.PathUnescape(submatches[i+1])
+			if err != nil {
+				value = submatches[i+1]
+			}
+			values[name] = value
+		}
+		return match{
+			route:       route.route,
+			verb:        verb,
+			pathSpec:    route.spec,
+			operation:   op,
+			definitions: r.definitions,
+			components:  r.parameters,
+			pathValues:  values,
+		}, http.StatusOK
+	}
+	return match{}, status
+}
@@ -0,0 +1,42 @@
+// Callbacks, i.e. requests that the API sends to its clients
+// as part of an operation.
+package callback
+
+import (
+	"github.com/pasqal-io/gousset/shared"
+)
+
+// Callback Object | Reference Object
+type Callback interface {
+	sealed()
+}
+
+// A path item, as described by `path.Spec`.
+//
+// This interface exists only to avoid a dependency cycle between
+// `path` and `operation`.
+type PathItem interface {
+	IsPathItem()
+}
+
+// https://spec.openapis.org/oas/v3.0.1.html#callback-object
+//
+// The key is a runtime expression (or a template containing runtime
+// expressions, see `expression.ValidateTemplate`) that identifies the
+// URL to use for the callback, e.g. `{$request.body#/callbackUrl}`.
+type Spec map[string]PathItem
+
+func (Spec) sealed() {}
+
+var _ Callback = Spec{}
+
+// A reference to a Component.
+type Reference shared.Reference
+
+func Ref(to string) Reference {
+	return Reference(shared.Ref(to))
+}
+
+func (Reference) sealed() {}
+
+var _ Callback = Reference{}
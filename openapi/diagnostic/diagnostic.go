@@ -0,0 +1,178 @@
+// Problems found while building a spec that do not prevent building it,
+// e.g. a parameter without a description.
+//
+// Diagnostics are collected in a `Report`. Functions that receive a nil
+// `*Report` log their diagnostics with `slog` instead.
+//
+// Problems that do prevent building a spec are reported as `Error`s,
+// which carry their location.
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// How serious a diagnostic is.
+type Severity string
+
+const (
+	// The spec is valid but less useful than it could be.
+	SeverityWarning = Severity("warning")
+
+	// A warning promoted to an error, see `openapi.Implementation.StrictCodes`.
+	SeverityError = Severity("error")
+)
+
+// The category of a diagnostic.
+type Code string
+
+const (
+	// `Info.Title` is empty.
+	CodeMissingTitle = Code("missing-title")
+
+	// A parameter, or `Info`, has no description.
+	CodeMissingDescription = Code("missing-description")
+
+	// A parameter has no tag with a public name, a default name is used.
+	CodeMissingPublicName = Code("missing-public-name")
+
+	// A struct has a single variant, tag `variant` is probably misapplied.
+	CodeMisappliedVariant = Code("misapplied-variant")
+
+	// A header parameter is ignored by OpenAPI, e.g. `Authorization`.
+	CodeReservedHeader = Code("reserved-header")
+
+	// An example does not conform to its schema.
+	CodeExampleMismatch = Code("example-mismatch")
+)
+
+// A problem found while building a spec.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     Code     `json:"code"`
+
+	// A human-readable description of the problem.
+	Message string `json:"message"`
+
+	// The operation in which the problem was found, e.g. `get /users/{id}`,
+	// or the path, for parameters shared by all operations of a path.
+	Endpoint string `json:"endpoint,omitempty" exhaustruct:"optional"`
+
+	// The Go type in which the problem was found, e.g. `main.UserQuery`.
+	Type string `json:"type,omitempty" exhaustruct:"optional"`
+
+	// The field of `Type` in which the problem was found.
+	Field string `json:"field,omitempty" exhaustruct:"optional"`
+
+	// A JSON pointer to the problem within the spec, if known.
+	Location string `json:"location,omitempty" exhaustruct:"optional"`
+}
+
+func (d Diagnostic) Error() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "%s [%s]", d.Severity, d.Code)
+	if d.Endpoint != "" {
+		fmt.Fprintf(&builder, " in %s", d.Endpoint)
+	}
+	if d.Type != "" {
+		fmt.Fprintf(&builder, " in %s", d.Type)
+		if d.Field != "" {
+			fmt.Fprintf(&builder, ".%s", d.Field)
+		}
+	}
+	if d.Location != "" {
+		fmt.Fprintf(&builder, " at %s", d.Location)
+	}
+	fmt.Fprintf(&builder, ": %s", d.Message)
+	return builder.String()
+}
+
+// Log a diagnostic with `slog`.
+func (d Diagnostic) Log(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := slog.LevelWarn
+	if d.Severity == SeverityError {
+		level = slog.LevelError
+	}
+	attrs := []any{"code", d.Code}
+	for _, attr := range []struct{ key, value string }{
+		{"endpoint", d.Endpoint},
+		{"type", d.Type},
+		{"field", d.Field},
+		{"location", d.Location},
+	} {
+		if attr.value != "" {
+			attrs = append(attrs, attr.key, attr.value)
+		}
+	}
+	logger.Log(context.Background(), level, "gousset: "+d.Message, attrs...)
+}
+
+// A collection of diagnostics.
+type Report struct {
+	// Shared between a report and the reports derived with `ForEndpoint`.
+	// If nil, diagnostics are logged.
+	diagnostics *[]Diagnostic
+
+	// The endpoint attached to diagnostics that do not specify one.
+	endpoint string
+}
+
+func NewReport() *Report {
+	return &Report{
+		diagnostics: &[]Diagnostic{},
+		endpoint:    "",
+	}
+}
+
+// A report adding diagnostics to `r`, attached to an endpoint.
+//
+// If `r` is already attached to an endpoint, e.g. for callbacks, the
+// endpoints are joined with a space, as operation ids.
+//
+// If `r` is nil, the diagnostics are logged.
+func (r *Report) ForEndpoint(endpoint string) *Report {
+	result := Report{
+		diagnostics: nil,
+		endpoint:    endpoint,
+	}
+	if r != nil {
+		result.diagnostics = r.diagnostics
+		if r.endpoint != "" {
+			result.endpoint = fmt.Sprint(r.endpoint, " ", endpoint)
+		}
+	}
+	return &result
+}
+
+// Add a diagnostic.
+//
+// If `r` is nil, log it instead.
+func (r *Report) Add(d Diagnostic) {
+	if d.Severity == "" {
+		d.Severity = SeverityWarning
+	}
+	if r != nil && d.Endpoint == "" {
+		d.Endpoint = r.endpoint
+	}
+	if r == nil || r.diagnostics == nil {
+		d.Log(nil)
+		return
+	}
+	*r.diagnostics = append(*r.diagnostics, d)
+}
+
+// The diagnostics added so far, in order.
+func (r *Report) Diagnostics() []Diagnostic {
+	if r == nil || r.diagnostics == nil {
+		return nil
+	}
+	result := make([]Diagnostic, len(*r.diagnostics))
+	copy(result, *r.diagnostics)
+	return result
+}
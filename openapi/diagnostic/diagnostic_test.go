@@ -0,0 +1,63 @@
+package diagnostic_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
+	"gotest.tools/assert"
+)
+
+func TestReport(t *testing.T) {
+	report := diagnostic.NewReport()
+	report.Add(diagnostic.Diagnostic{
+		Code:    diagnostic.CodeMissingTitle,
+		Message: "missing title",
+	})
+	operation := report.ForEndpoint("get /users")
+	operation.Add(diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityWarning,
+		Code:     diagnostic.CodeMissingDescription,
+		Message:  "missing description",
+		Type:     "main.UserQuery",
+		Field:    "Page",
+	})
+	operation.ForEndpoint("onEvent").ForEndpoint("post {$request.body#/url}").Add(diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityWarning,
+		Code:     diagnostic.CodeMissingPublicName,
+		Message:  "missing public name",
+	})
+
+	diagnostics := report.Diagnostics()
+	assert.Equal(t, len(diagnostics), 3)
+	assert.Equal(t, diagnostics[0].Error(), "warning [missing-title]: missing title")
+	assert.Equal(t, diagnostics[1].Error(), "warning [missing-description] in get /users in main.UserQuery.Page: missing description")
+	assert.Equal(t, diagnostics[2].Endpoint, "get /users onEvent post {$request.body#/url}")
+
+	// Without a report, diagnostics are logged.
+	var nothing *diagnostic.Report
+	nothing.ForEndpoint("get /users").Add(diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityWarning,
+		Code:     diagnostic.CodeMissingTitle,
+		Message:  "missing title",
+	})
+	assert.Assert(t, nothing.Diagnostics() == nil)
+}
+
+func TestErrors(t *testing.T) {
+	assert.NilError(t, diagnostic.Join())
+	assert.NilError(t, diagnostic.Join(nil, nil))
+
+	inner := diagnostic.Error{Type: "main.Address", Field: "City", Err: errors.New("missing public name")}
+	unlocated := errors.New("unknown style")
+	err := diagnostic.Locate(diagnostic.Join(inner, unlocated), diagnostic.Error{Path: "/users", Verb: "post", Type: "main.User", Field: "Address"})
+	err = diagnostic.Wrap(err, "while compiling body")
+	assert.Error(t, err, "in operation post /users, in field main.Address.City: while compiling body: missing public name\n"+
+		"in operation post /users, in field main.User.Address: while compiling body: unknown style")
+	assert.Assert(t, errors.Is(err, unlocated))
+
+	// Joined errors are flattened.
+	joined, ok := diagnostic.Join(err, errors.New("invalid path")).(interface{ Unwrap() []error })
+	assert.Assert(t, ok)
+	assert.Equal(t, len(joined.Unwrap()), 3)
+}
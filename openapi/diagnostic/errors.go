@@ -0,0 +1,126 @@
+package diagnostic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// An error found while building a spec, with its location.
+//
+// `openapi.FromImplementation` collects all the errors it finds into a single
+// error, use `errors.As` to extract them.
+type Error struct {
+	// The path of the endpoint, as provided, e.g. `/users/:id`.
+	Path string `exhaustruct:"optional"`
+
+	// The verb of the operation, e.g. `get`.
+	Verb string `exhaustruct:"optional"`
+
+	// The Go type in which the error was found, e.g. `main.UserQuery`.
+	Type string `exhaustruct:"optional"`
+
+	// The field of `Type` in which the error was found.
+	Field string `exhaustruct:"optional"`
+
+	Err error
+}
+
+func (e Error) Error() string {
+	var location []string
+	switch {
+	case e.Verb != "":
+		location = append(location, fmt.Sprint("in operation ", e.Verb, " ", e.Path))
+	case e.Path != "":
+		location = append(location, fmt.Sprint("at ", e.Path))
+	}
+	switch {
+	case e.Field != "":
+		location = append(location, fmt.Sprint("in field ", e.Type, ".", e.Field))
+	case e.Type != "":
+		location = append(location, fmt.Sprint("in type ", e.Type))
+	}
+	if len(location) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprint(strings.Join(location, ", "), ": ", e.Err)
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// The errors joined in `err`, recursively.
+func leaves(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var result []error
+	for _, child := range joined.Unwrap() {
+		result = append(result, leaves(child)...)
+	}
+	return result
+}
+
+// As `errors.Join`, but flattening joined errors, so that each of them
+// is reported on its own line, and returning single errors unchanged.
+func Join(errs ...error) error {
+	var flattened []error
+	for _, err := range errs {
+		flattened = append(flattened, leaves(err)...)
+	}
+	switch len(flattened) {
+	case 0:
+		return nil
+	case 1:
+		return flattened[0]
+	default:
+		return errors.Join(flattened...)
+	}
+}
+
+// Attach a location to each of the errors joined in `err`.
+//
+// Locations already attached to an `Error` take precedence, as they are
+// more precise, e.g. the field of a nested struct rather than the field
+// containing the struct.
+func Locate(err error, at Error) error {
+	var result []error
+	for _, leaf := range leaves(err) {
+		located, ok := leaf.(Error)
+		if !ok {
+			located = at
+			located.Err = leaf
+		} else {
+			if located.Path == "" && located.Verb == "" {
+				located.Path = at.Path
+				located.Verb = at.Verb
+			}
+			if located.Type == "" && located.Field == "" {
+				located.Type = at.Type
+				located.Field = at.Field
+			}
+		}
+		result = append(result, located)
+	}
+	return Join(result...)
+}
+
+// Prefix each of the errors joined in `err` with some context, e.g.
+// "while compiling response", keeping their location.
+func Wrap(err error, context string) error {
+	var result []error
+	for _, leaf := range leaves(err) {
+		if located, ok := leaf.(Error); ok {
+			located.Err = fmt.Errorf("%s: %w", context, located.Err)
+			result = append(result, located)
+			continue
+		}
+		result = append(result, fmt.Errorf("%s: %w", context, leaf))
+	}
+	return Join(result...)
+}
@@ -0,0 +1,423 @@
+// Detect changes between two versions of an OpenAPI spec and classify
+// them as breaking or non-breaking for existing clients.
+//
+// As a rule of thumb, a change is breaking if it accepts fewer requests
+// (e.g. a removed operation, a newly required parameter, a narrowed enum)
+// or produces more varied responses (e.g. a removed response field, a new
+// enum value).
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi"
+	"gopkg.in/yaml.v3"
+)
+
+// The kind of a change.
+type Kind string
+
+const (
+	KindOperationRemoved    = Kind("operation-removed")
+	KindOperationAdded      = Kind("operation-added")
+	KindParameterRemoved    = Kind("parameter-removed")
+	KindParameterAdded      = Kind("parameter-added")
+	KindParameterRequired   = Kind("parameter-required")
+	KindParameterOptional   = Kind("parameter-optional")
+	KindRequestBodyRequired = Kind("request-body-required")
+	KindRequestBodyOptional = Kind("request-body-optional")
+	KindMediaTypeRemoved    = Kind("media-type-removed")
+	KindMediaTypeAdded      = Kind("media-type-added")
+	KindResponseRemoved     = Kind("response-removed")
+	KindResponseAdded       = Kind("response-added")
+	KindHeaderRemoved       = Kind("header-removed")
+	KindHeaderAdded         = Kind("header-added")
+	KindTypeChanged         = Kind("type-changed")
+	KindEnumNarrowed        = Kind("enum-narrowed")
+	KindEnumWidened         = Kind("enum-widened")
+	KindBoundTightened      = Kind("bound-tightened")
+	KindBoundRelaxed        = Kind("bound-relaxed")
+	KindPatternChanged      = Kind("pattern-changed")
+	KindPropertyRemoved     = Kind("property-removed")
+	KindPropertyAdded       = Kind("property-added")
+	KindPropertyRequired    = Kind("property-required")
+	KindPropertyOptional    = Kind("property-optional")
+	KindVariantRemoved      = Kind("variant-removed")
+	KindVariantAdded        = Kind("variant-added")
+	KindSecurityChanged     = Kind("security-changed")
+)
+
+// A change between two versions of a spec.
+type Change struct {
+	// What changed.
+	Kind Kind `json:"kind"`
+
+	// `true` if existing clients may stop working.
+	Breaking bool `json:"breaking"`
+
+	// A JSON pointer to the change within the spec, e.g.
+	// `/paths/~1users/get/parameters/query/verbose`.
+	Location string `json:"location"`
+
+	// A human-readable explanation.
+	Message string `json:"message"`
+}
+
+func (c Change) String() string {
+	severity := "non-breaking"
+	if c.Breaking {
+		severity = "BREAKING"
+	}
+	return fmt.Sprintf("%s: %s at %s: %s", severity, c.Kind, c.Location, c.Message)
+}
+
+// All the changes between two versions of a spec, sorted by location.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Return the breaking changes of the report.
+func (r Report) Breaking() []Change {
+	var result []Change
+	for _, change := range r.Changes {
+		if change.Breaking {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// Return `true` if the report contains at least one breaking change.
+func (r Report) HasBreaking() bool {
+	return slices.ContainsFunc(r.Changes, func(c Change) bool { return c.Breaking })
+}
+
+// Compare two versions of a spec.
+func Compare(old openapi.Spec, new openapi.Spec) (Report, error) {
+	oldDoc, err := toDocument(old)
+	if err != nil {
+		return Report{}, fmt.Errorf("while comparing specs, failed to serialize old spec: %w", err)
+	}
+	newDoc, err := toDocument(new)
+	if err != nil {
+		return Report{}, fmt.Errorf("while comparing specs, failed to serialize new spec: %w", err)
+	}
+	return compareDocuments(oldDoc, newDoc), nil
+}
+
+// Compare two versions of a spec, serialized as JSON or YAML.
+func CompareDocuments(old []byte, new []byte) (Report, error) {
+	oldDoc, err := parseDocument(old)
+	if err != nil {
+		return Report{}, fmt.Errorf("while comparing specs, failed to parse old spec: %w", err)
+	}
+	newDoc, err := parseDocument(new)
+	if err != nil {
+		return Report{}, fmt.Errorf("while comparing specs, failed to parse new spec: %w", err)
+	}
+	return compareDocuments(oldDoc, newDoc), nil
+}
+
+func toDocument(spec openapi.Spec) (map[string]any, error) {
+	buf, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]any
+	if err := json.Unmarshal(buf, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseDocument(buf []byte) (map[string]any, error) {
+	// JSON is a subset of YAML.
+	var parsed any
+	if err := yaml.Unmarshal(buf, &parsed); err != nil {
+		return nil, err
+	}
+	result, ok := normalize(parsed).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", parsed)
+	}
+	return result, nil
+}
+
+// Convert YAML maps with non-string keys (e.g. `200:`) to `map[string]any`.
+func normalize(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for k, v := range value {
+			value[k] = normalize(v)
+		}
+		return value
+	case map[any]any:
+		result := make(map[string]any, len(value))
+		for k, v := range value {
+			result[fmt.Sprint(k)] = normalize(v)
+		}
+		return result
+	case []any:
+		for i, v := range value {
+			value[i] = normalize(v)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// Accumulate changes while walking two documents side by side.
+type differ struct {
+	oldDoc  map[string]any
+	newDoc  map[string]any
+	changes []Change
+}
+
+func (d *differ) report(kind Kind, breaking bool, location string, format string, args ...any) {
+	d.changes = append(d.changes, Change{
+		Kind:     kind,
+		Breaking: breaking,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func compareDocuments(oldDoc map[string]any, newDoc map[string]any) Report {
+	d := differ{
+		oldDoc: oldDoc,
+		newDoc: newDoc,
+	}
+	for _, section := range []string{"paths", "webhooks"} {
+		oldPaths := object(oldDoc[section])
+		newPaths := object(newDoc[section])
+		for _, route := range sortedKeys(oldPaths) {
+			d.comparePath(object(oldPaths[route]), object(newPaths[route]), appendPointer("/"+section, route))
+		}
+		for _, route := range sortedKeys(newPaths) {
+			if _, ok := oldPaths[route]; !ok {
+				d.comparePath(nil, object(newPaths[route]), appendPointer("/"+section, route))
+			}
+		}
+	}
+	d.compareSecuritySchemes()
+	slices.SortStableFunc(d.changes, func(a, b Change) int {
+		return strings.Compare(a.Location, b.Location)
+	})
+	return Report{Changes: d.changes}
+}
+
+var verbs = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func (d *differ) comparePath(oldPath map[string]any, newPath map[string]any, location string) {
+	for _, verb := range verbs {
+		oldOp, oldOk := oldPath[verb].(map[string]any)
+		newOp, newOk := newPath[verb].(map[string]any)
+		here := appendPointer(location, verb)
+		switch {
+		case oldOk && !newOk:
+			d.report(KindOperationRemoved, true, here, "operation %s was removed", describeOperation(oldOp, verb))
+		case !oldOk && newOk:
+			d.report(KindOperationAdded, false, here, "operation %s was added", describeOperation(newOp, verb))
+		case oldOk && newOk:
+			d.compareOperation(oldPath, oldOp, newPath, newOp, here)
+		}
+	}
+}
+
+func describeOperation(op map[string]any, verb string) string {
+	if id, ok := op["operationId"].(string); ok {
+		return fmt.Sprintf(`"%s"`, id)
+	}
+	return verb
+}
+
+func (d *differ) compareOperation(oldPath map[string]any, oldOp map[string]any, newPath map[string]any, newOp map[string]any, location string) {
+	d.compareParameters(d.parameters(d.oldDoc, oldPath, oldOp), d.parameters(d.newDoc, newPath, newOp), appendPointer(location, "parameters"))
+	d.compareRequestBody(resolve(d.oldDoc, oldOp["requestBody"]), resolve(d.newDoc, newOp["requestBody"]), appendPointer(location, "requestBody"))
+	d.compareResponses(object(oldOp["responses"]), object(newOp["responses"]), appendPointer(location, "responses"))
+	d.compareSecurity(d.security(d.oldDoc, oldOp), d.security(d.newDoc, newOp), appendPointer(location, "security"))
+}
+
+// The parameters of an operation, including those declared at path level,
+// indexed by "in/name".
+func (d *differ) parameters(doc map[string]any, pathItem map[string]any, op map[string]any) map[string]map[string]any {
+	result := make(map[string]map[string]any)
+	for _, source := range []any{pathItem["parameters"], op["parameters"]} {
+		list, _ := source.([]any)
+		for _, item := range list {
+			param := object(resolve(doc, item))
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			result[in+"/"+name] = param
+		}
+	}
+	return result
+}
+
+func (d *differ) compareParameters(oldParams map[string]map[string]any, newParams map[string]map[string]any, location string) {
+	for _, key := range sortedKeys(oldParams) {
+		here := appendPointer(location, strings.SplitN(key, "/", 2)...)
+		oldParam := oldParams[key]
+		newParam, ok := newParams[key]
+		if !ok {
+			d.report(KindParameterRemoved, true, here, "parameter %s was removed", key)
+			continue
+		}
+		oldRequired, _ := oldParam["required"].(bool)
+		newRequired, _ := newParam["required"].(bool)
+		switch {
+		case !oldRequired && newRequired:
+			d.report(KindParameterRequired, true, here, "parameter %s is now required", key)
+		case oldRequired && !newRequired:
+			d.report(KindParameterOptional, false, here, "parameter %s is now optional", key)
+		}
+		d.compareSchema(oldParam["schema"], newParam["schema"], appendPointer(here, "schema"), directionRequest)
+		d.compareContent(object(oldParam["content"]), object(newParam["content"]), appendPointer(here, "content"), directionRequest)
+	}
+	for _, key := range sortedKeys(newParams) {
+		if _, ok := oldParams[key]; ok {
+			continue
+		}
+		required, _ := newParams[key]["required"].(bool)
+		here := appendPointer(location, strings.SplitN(key, "/", 2)...)
+		if required {
+			d.report(KindParameterAdded, true, here, "required parameter %s was added", key)
+		} else {
+			d.report(KindParameterAdded, false, here, "optional parameter %s was added", key)
+		}
+	}
+}
+
+func (d *differ) compareRequestBody(oldBody any, newBody any, location string) {
+	oldObj, oldOk := oldBody.(map[string]any)
+	newObj, newOk := newBody.(map[string]any)
+	oldRequired, _ := oldObj["required"].(bool)
+	newRequired, _ := newObj["required"].(bool)
+	switch {
+	case !oldOk && !newOk:
+		return
+	case !oldOk:
+		d.report(KindRequestBodyRequired, newRequired, location, "a request body was added")
+		return
+	case !newOk:
+		d.report(KindRequestBodyOptional, false, location, "the request body was removed")
+		return
+	}
+	switch {
+	case !oldRequired && newRequired:
+		d.report(KindRequestBodyRequired, true, location, "the request body is now required")
+	case oldRequired && !newRequired:
+		d.report(KindRequestBodyOptional, false, location, "the request body is now optional")
+	}
+	d.compareContent(object(oldObj["content"]), object(newObj["content"]), appendPointer(location, "content"), directionRequest)
+}
+
+func (d *differ) compareContent(oldContent map[string]any, newContent map[string]any, location string, direction direction) {
+	for _, mediaType := range sortedKeys(oldContent) {
+		here := appendPointer(location, mediaType)
+		newMedia, ok := newContent[mediaType]
+		if !ok {
+			// Clients may not be able to send or understand another media type.
+			d.report(KindMediaTypeRemoved, true, here, "media type %s was removed", mediaType)
+			continue
+		}
+		d.compareSchema(object(oldContent[mediaType])["schema"], object(newMedia)["schema"], appendPointer(here, "schema"), direction)
+	}
+	for _, mediaType := range sortedKeys(newContent) {
+		if _, ok := oldContent[mediaType]; !ok {
+			d.report(KindMediaTypeAdded, direction == directionResponse, appendPointer(location, mediaType), "media type %s was added", mediaType)
+		}
+	}
+}
+
+func (d *differ) compareResponses(oldResponses map[string]any, newResponses map[string]any, location string) {
+	for _, code := range sortedKeys(oldResponses) {
+		here := appendPointer(location, code)
+		newResponse, ok := newResponses[code]
+		if !ok {
+			d.report(KindResponseRemoved, false, here, "response %s was removed", code)
+			continue
+		}
+		oldObj := object(resolve(d.oldDoc, oldResponses[code]))
+		newObj := object(resolve(d.newDoc, newResponse))
+		d.compareContent(object(oldObj["content"]), object(newObj["content"]), appendPointer(here, "content"), directionResponse)
+
+		oldHeaders := object(oldObj["headers"])
+		newHeaders := object(newObj["headers"])
+		for _, name := range sortedKeys(oldHeaders) {
+			headerHere := appendPointer(here, "headers", name)
+			newHeader, ok := newHeaders[name]
+			if !ok {
+				d.report(KindHeaderRemoved, true, headerHere, "response header %s was removed", name)
+				continue
+			}
+			d.compareSchema(object(resolve(d.oldDoc, oldHeaders[name]))["schema"], object(resolve(d.newDoc, newHeader))["schema"], appendPointer(headerHere, "schema"), directionResponse)
+		}
+		for _, name := range sortedKeys(newHeaders) {
+			if _, ok := oldHeaders[name]; !ok {
+				d.report(KindHeaderAdded, false, appendPointer(here, "headers", name), "response header %s was added", name)
+			}
+		}
+	}
+	for _, code := range sortedKeys(newResponses) {
+		if _, ok := oldResponses[code]; !ok {
+			d.report(KindResponseAdded, false, appendPointer(location, code), "response %s was added", code)
+		}
+	}
+}
+
+// The security requirements of an operation, as canonical JSON strings.
+//
+// No requirement is represented as the empty requirement `{}`.
+func (d *differ) security(doc map[string]any, op map[string]any) []string {
+	requirements, ok := op["security"].([]any)
+	if !ok {
+		requirements, ok = doc["security"].([]any)
+	}
+	if !ok || len(requirements) == 0 {
+		return []string{"{}"}
+	}
+	result := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		result = append(result, canonical(requirement))
+	}
+	return result
+}
+
+func (d *differ) compareSecurity(oldSecurity []string, newSecurity []string, location string) {
+	for _, requirement := range oldSecurity {
+		if !slices.Contains(newSecurity, requirement) {
+			d.report(KindSecurityChanged, true, location, "security requirement %s was removed", requirement)
+		}
+	}
+	for _, requirement := range newSecurity {
+		if !slices.Contains(oldSecurity, requirement) {
+			d.report(KindSecurityChanged, false, location, "security requirement %s was added", requirement)
+		}
+	}
+}
+
+func (d *differ) compareSecuritySchemes() {
+	oldSchemes := object(object(d.oldDoc["components"])["securitySchemes"])
+	newSchemes := object(object(d.newDoc["components"])["securitySchemes"])
+	for _, name := range sortedKeys(oldSchemes) {
+		here := appendPointer("/components/securitySchemes", name)
+		newScheme, ok := newSchemes[name]
+		if !ok {
+			d.report(KindSecurityChanged, true, here, "security scheme %s was removed", name)
+			continue
+		}
+		if canonical(oldSchemes[name]) != canonical(newScheme) {
+			d.report(KindSecurityChanged, true, here, "security scheme %s was changed", name)
+		}
+	}
+	for _, name := range sortedKeys(newSchemes) {
+		if _, ok := oldSchemes[name]; !ok {
+			d.report(KindSecurityChanged, false, appendPointer("/components/securitySchemes", name), "security scheme %s was added", name)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/diff"
+	"github.com/pasqal-io/gousset/openapi/media"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/response"
+	"github.com/pasqal-io/gousset/openapi/security"
+	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
+)
+
+type Status string
+
+type OldPath struct {
+	Id string `path:"id" description:"The id of the user"`
+}
+
+type OldQuery struct {
+	Verbose bool `query:"verbose" description:"More details" default:"false"`
+	Legacy  bool `query:"legacy" description:"Legacy mode" default:"false"`
+}
+
+type OldInput struct {
+	Path  OldPath
+	Query OldQuery
+	Body  OldBody
+}
+
+type OldBody struct {
+	Name   string `json:"name" maxLength:"20"`
+	Status Status `json:"status" default:"active"`
+}
+
+type OldUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (Status) Enum() []any {
+	return []any{"active", "closed"}
+}
+
+type NewQuery struct {
+	Verbose bool `query:"verbose" description:"More details"`
+}
+
+type NewInput struct {
+	Path  OldPath
+	Query NewQuery
+	Body  NewBody
+}
+
+type NewStatus string
+
+func (NewStatus) Enum() []any {
+	return []any{"active"}
+}
+
+type NewBody struct {
+	Name     string    `json:"name" maxLength:"10"`
+	Status   NewStatus `json:"status" default:"active"`
+	Nickname string    `json:"nickname"`
+}
+
+type NewUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func makeSpec(t *testing.T, input reflect.Type, output reflect.Type, requirements []security.Requirement, extra bool) openapi.Spec {
+	perVerb := map[path.Verb]path.VerbImplementation{
+		path.Put: {
+			Input:    input,
+			Security: requirements,
+			Response: response.Implementation{
+				Default: response.ResponseImplementation{
+					Description: "The user",
+					Content: &map[string]media.Implementation{
+						"application/json": {Type: output},
+					},
+				},
+			},
+		},
+	}
+	if extra {
+		perVerb[path.Delete] = path.VerbImplementation{
+			Input: reflect.TypeFor[struct{ Path OldPath }](),
+		}
+	}
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path:    "/users/:id",
+				PerVerb: perVerb,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	return spec
+}
+
+func TestCompareIdentical(t *testing.T) {
+	spec := makeSpec(t, reflect.TypeFor[OldInput](), reflect.TypeFor[OldUser](), nil, true)
+	report, err := diff.Compare(spec, spec)
+	assert.NilError(t, err)
+	assert.Equal(t, len(report.Changes), 0)
+	assert.Assert(t, !report.HasBreaking())
+}
+
+func TestCompare(t *testing.T) {
+	old := makeSpec(t, reflect.TypeFor[OldInput](), reflect.TypeFor[OldUser](), nil, true)
+	new := makeSpec(t, reflect.TypeFor[NewInput](), reflect.TypeFor[NewUser](), []security.Requirement{{"oauth": {"users"}}}, false)
+	report, err := diff.Compare(old, new)
+	assert.NilError(t, err)
+	assert.Assert(t, report.HasBreaking())
+	testutils.EqualJSON(t, report, `{
+		"changes": [
+			{
+				"kind": "operation-removed",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/delete",
+				"message": "operation \"delete /users/:id\" was removed"
+			},
+			{
+				"kind": "parameter-removed",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/parameters/query/legacy",
+				"message": "parameter query/legacy was removed"
+			},
+			{
+				"kind": "parameter-required",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/parameters/query/verbose",
+				"message": "parameter query/verbose is now required"
+			},
+			{
+				"kind": "bound-tightened",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/requestBody/content/application~1json/schema/properties/name",
+				"message": "maxLength was tightened from 20 to 10"
+			},
+			{
+				"kind": "property-added",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/requestBody/content/application~1json/schema/properties/nickname",
+				"message": "property nickname was added"
+			},
+			{
+				"kind": "enum-narrowed",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/requestBody/content/application~1json/schema/properties/status",
+				"message": "enum values \"closed\" were removed"
+			},
+			{
+				"kind": "property-added",
+				"breaking": false,
+				"location": "/paths/~1users~1{id}/put/responses/default/content/application~1json/schema/properties/age",
+				"message": "property age was added"
+			},
+			{
+				"kind": "property-removed",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/responses/default/content/application~1json/schema/properties/email",
+				"message": "property email was removed"
+			},
+			{
+				"kind": "security-changed",
+				"breaking": true,
+				"location": "/paths/~1users~1{id}/put/security",
+				"message": "security requirement {} was removed"
+			},
+			{
+				"kind": "security-changed",
+				"breaking": false,
+				"location": "/paths/~1users~1{id}/put/security",
+				"message": "security requirement {\"oauth\":[\"users\"]} was added"
+			}
+		]
+	}`)
+}
+
+// Test that the direction matters and that YAML documents with references are supported.
+func TestCompareDocuments(t *testing.T) {
+	old := `
+openapi: 3.0.1
+paths:
+  /status:
+    get:
+      responses:
+        200:
+          description: The status
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Status"
+components:
+  schemas:
+    Status:
+      type: object
+      required: [state]
+      properties:
+        state:
+          type: string
+          enum: [up, down]
+        uptime:
+          type: integer
+          minimum: 0
+`
+	new := `{
+		"openapi": "3.0.1",
+		"paths": {
+			"/status": {
+				"get": {
+					"responses": {
+						"200": {
+							"description": "The status",
+							"content": {
+								"application/json": {
+									"schema": {"$ref": "#/components/schemas/Status"}
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"components": {
+			"schemas": {
+				"Status": {
+					"type": "object",
+					"properties": {
+						"state": {"type": "string", "enum": ["up", "down", "degraded"]},
+						"uptime": {"type": "number", "minimum": 0}
+					}
+				}
+			}
+		}
+	}`
+	report, err := diff.CompareDocuments([]byte(old), []byte(new))
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, report.Breaking(), `[
+		{
+			"kind": "property-optional",
+			"breaking": true,
+			"location": "/paths/~1status/get/responses/200/content/application~1json/schema/properties/state",
+			"message": "property state is now optional"
+		},
+		{
+			"kind": "enum-widened",
+			"breaking": true,
+			"location": "/paths/~1status/get/responses/200/content/application~1json/schema/properties/state",
+			"message": "enum values \"degraded\" were added"
+		},
+		{
+			"kind": "type-changed",
+			"breaking": true,
+			"location": "/paths/~1status/get/responses/200/content/application~1json/schema/properties/uptime",
+			"message": "type changed from \"integer\" to \"number\""
+		}
+	]`)
+}
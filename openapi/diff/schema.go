@@ -0,0 +1,302 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Whether a schema describes data sent by the client or by the server.
+//
+// Narrowing the data accepted by the server breaks clients, as does
+// widening the data sent by the server.
+type direction int
+
+const (
+	directionRequest direction = iota
+	directionResponse
+)
+
+// Return `true` if a change that narrows (or widens) the set of values
+// is breaking in this direction.
+func (d direction) breaks(narrowing bool) bool {
+	return narrowing == (d == directionRequest)
+}
+
+func object(value any) map[string]any {
+	result, _ := value.(map[string]any)
+	return result
+}
+
+// Follow a `$ref` within the document, e.g. `#/components/schemas/User`.
+//
+// Returns the value itself if it is not a reference or the reference cannot be resolved.
+func resolve(doc map[string]any, value any) any {
+	for range 32 { // Arbitrary limit, to protect against cycles of references.
+		ref, ok := object(value)["$ref"].(string)
+		if !ok {
+			return value
+		}
+		target, ok := lookup(doc, ref)
+		if !ok {
+			return value
+		}
+		value = target
+	}
+	return value
+}
+
+// Find the value at a local reference, e.g. `#/components/schemas/User`.
+func lookup(doc map[string]any, ref string) (any, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	var current any = doc
+	for _, segment := range strings.Split(pointer, "/")[1:] {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		next, ok := object(current)[segment]
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// Extend a JSON pointer with segments.
+func appendPointer(pointer string, segments ...string) string {
+	builder := strings.Builder{}
+	builder.WriteString(pointer)
+	for _, segment := range segments {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
+
+// Return the keys of a map, sorted, to make reports reproducible.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// A canonical representation of a JSON value, for comparison.
+func canonical(value any) string {
+	// encoding/json sorts the keys of maps.
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(buf)
+}
+
+func number(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// A human-readable bound, possibly missing.
+func bound(value any) string {
+	if value == nil {
+		return "none"
+	}
+	return fmt.Sprint(value)
+}
+
+func stringSet(value any) []string {
+	list, _ := value.([]any)
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		result = append(result, canonical(item))
+	}
+	return result
+}
+
+// Lower bounds: increasing them narrows the set of values.
+var lowerBounds = []string{"minimum", "exclusiveMinimum", "minLength", "minItems", "minProperties"}
+
+// Upper bounds: decreasing them narrows the set of values.
+var upperBounds = []string{"maximum", "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"}
+
+// A pair of referenced schemas being compared, to protect against recursive schemas.
+type visit struct {
+	old string
+	new string
+}
+
+func (d *differ) compareSchema(oldSchema any, newSchema any, location string, direction direction) {
+	d.compareSchemaRec(oldSchema, newSchema, location, direction, make(map[visit]bool))
+}
+
+func (d *differ) compareSchemaRec(oldSchema any, newSchema any, location string, direction direction, visited map[visit]bool) {
+	// Avoid looping on recursive types.
+	oldRef, _ := object(oldSchema)["$ref"].(string)
+	newRef, _ := object(newSchema)["$ref"].(string)
+	if oldRef != "" || newRef != "" {
+		key := visit{old: oldRef, new: newRef}
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		defer delete(visited, key)
+	}
+	oldObj := object(resolve(d.oldDoc, oldSchema))
+	newObj := object(resolve(d.newDoc, newSchema))
+	if oldObj == nil || newObj == nil {
+		// Either no schema or a boolean schema, which we do not compare.
+		return
+	}
+
+	// Type and format.
+	for _, keyword := range []string{"type", "format"} {
+		oldValue, newValue := oldObj[keyword], newObj[keyword]
+		if oldValue != nil && newValue != nil && canonical(oldValue) != canonical(newValue) {
+			d.report(KindTypeChanged, true, location, "%s changed from %s to %s", keyword, canonical(oldValue), canonical(newValue))
+			return
+		}
+	}
+
+	// Enum.
+	_, oldHasEnum := oldObj["enum"]
+	_, newHasEnum := newObj["enum"]
+	oldEnum, newEnum := stringSet(oldObj["enum"]), stringSet(newObj["enum"])
+	switch {
+	case oldHasEnum && newHasEnum:
+		var removed, added []string
+		for _, value := range oldEnum {
+			if !slices.Contains(newEnum, value) {
+				removed = append(removed, value)
+			}
+		}
+		for _, value := range newEnum {
+			if !slices.Contains(oldEnum, value) {
+				added = append(added, value)
+			}
+		}
+		if len(removed) != 0 {
+			d.report(KindEnumNarrowed, direction.breaks(true), location, "enum values %s were removed", strings.Join(removed, ", "))
+		}
+		if len(added) != 0 {
+			d.report(KindEnumWidened, direction.breaks(false), location, "enum values %s were added", strings.Join(added, ", "))
+		}
+	case !oldHasEnum && newHasEnum:
+		d.report(KindEnumNarrowed, direction.breaks(true), location, "values are now restricted to %s", strings.Join(newEnum, ", "))
+	case oldHasEnum && !newHasEnum:
+		d.report(KindEnumWidened, direction.breaks(false), location, "values are no longer restricted to an enum")
+	}
+
+	// Bounds.
+	for _, bounds := range []struct {
+		keywords []string
+		lower    bool
+	}{{lowerBounds, true}, {upperBounds, false}} {
+		for _, keyword := range bounds.keywords {
+			oldValue, oldOk := number(oldObj[keyword])
+			newValue, newOk := number(newObj[keyword])
+			var narrowed bool
+			switch {
+			case !oldOk && !newOk:
+				continue
+			case !oldOk:
+				narrowed = true
+			case !newOk:
+				narrowed = false
+			case oldValue == newValue:
+				continue
+			default:
+				narrowed = (newValue > oldValue) == bounds.lower
+			}
+			if narrowed {
+				d.report(KindBoundTightened, direction.breaks(true), location, "%s was tightened from %s to %s", keyword, bound(oldObj[keyword]), bound(newObj[keyword]))
+			} else {
+				d.report(KindBoundRelaxed, direction.breaks(false), location, "%s was relaxed from %s to %s", keyword, bound(oldObj[keyword]), bound(newObj[keyword]))
+			}
+		}
+	}
+
+	// Pattern.
+	oldPattern, _ := oldObj["pattern"].(string)
+	newPattern, _ := newObj["pattern"].(string)
+	if oldPattern != newPattern {
+		var breaking bool
+		switch {
+		case oldPattern == "":
+			breaking = direction.breaks(true)
+		case newPattern == "":
+			breaking = direction.breaks(false)
+		default:
+			// We cannot tell whether a pattern is narrower than another.
+			breaking = true
+		}
+		d.report(KindPatternChanged, breaking, location, "pattern changed from %q to %q", oldPattern, newPattern)
+	}
+
+	// Properties.
+	oldProperties := object(oldObj["properties"])
+	newProperties := object(newObj["properties"])
+	oldRequired := stringSet(oldObj["required"])
+	newRequired := stringSet(newObj["required"])
+	for _, name := range sortedKeys(oldProperties) {
+		here := appendPointer(location, "properties", name)
+		newProperty, ok := newProperties[name]
+		if !ok {
+			// Clients may rely on receiving the property, but servers ignore unknown properties.
+			d.report(KindPropertyRemoved, direction == directionResponse, here, "property %s was removed", name)
+			continue
+		}
+		quoted := canonical(name)
+		switch {
+		case !slices.Contains(oldRequired, quoted) && slices.Contains(newRequired, quoted):
+			d.report(KindPropertyRequired, direction.breaks(true), here, "property %s is now required", name)
+		case slices.Contains(oldRequired, quoted) && !slices.Contains(newRequired, quoted):
+			d.report(KindPropertyOptional, direction.breaks(false), here, "property %s is now optional", name)
+		}
+		d.compareSchemaRec(oldProperties[name], newProperty, here, direction, visited)
+	}
+	for _, name := range sortedKeys(newProperties) {
+		if _, ok := oldProperties[name]; ok {
+			continue
+		}
+		required := slices.Contains(newRequired, canonical(name))
+		d.report(KindPropertyAdded, required && direction == directionRequest, appendPointer(location, "properties", name), "property %s was added", name)
+	}
+
+	// Children.
+	if oldObj["items"] != nil && newObj["items"] != nil {
+		d.compareSchemaRec(oldObj["items"], newObj["items"], appendPointer(location, "items"), direction, visited)
+	}
+	if object(oldObj["additionalProperties"]) != nil && object(newObj["additionalProperties"]) != nil {
+		d.compareSchemaRec(oldObj["additionalProperties"], newObj["additionalProperties"], appendPointer(location, "additionalProperties"), direction, visited)
+	}
+	for _, combinator := range []string{"oneOf", "anyOf", "allOf"} {
+		oldVariants, _ := oldObj[combinator].([]any)
+		newVariants, _ := newObj[combinator].([]any)
+		for i := 0; i < min(len(oldVariants), len(newVariants)); i++ {
+			d.compareSchemaRec(oldVariants[i], newVariants[i], appendPointer(location, combinator, fmt.Sprint(i)), direction, visited)
+		}
+		// Additional `allOf` constraints narrow the set of values, additional `oneOf`/`anyOf` variants widen it.
+		narrowing := combinator == "allOf"
+		for i := len(newVariants); i < len(oldVariants); i++ {
+			d.report(KindVariantRemoved, direction.breaks(!narrowing), appendPointer(location, combinator, fmt.Sprint(i)), "%s variant %d was removed", combinator, i)
+		}
+		for i := len(oldVariants); i < len(newVariants); i++ {
+			d.report(KindVariantAdded, direction.breaks(narrowing), appendPointer(location, combinator, fmt.Sprint(i)), "%s variant %d was added", combinator, i)
+		}
+	}
+}
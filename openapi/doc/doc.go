@@ -2,6 +2,7 @@
 package doc
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/pasqal-io/gousset/shared"
@@ -52,6 +53,71 @@ func GetDescription(typ reflect.Type) *string {
 	return nil
 }
 
+// Utility: get the description from a HasDescription or, failing that,
+// from the doc comment of the type, if `comments` is not nil.
+func GetDescriptionOrComment(comments Comments, typ reflect.Type) *string {
+	if description := GetDescription(typ); description != nil {
+		return description
+	}
+	return GetTypeComment(comments, typ)
+}
+
+// Utility: get the doc comment of a type, if `comments` is not nil.
+func GetTypeComment(comments Comments, typ reflect.Type) *string {
+	if comments == nil {
+		return nil
+	}
+	if comment, ok := comments.TypeComment(typ); ok {
+		return &comment
+	}
+	return nil
+}
+
+// Utility: get the description of a field of a struct from its doc comment,
+// if `comments` is not nil.
+func GetFieldDescription(comments Comments, container reflect.Type, field reflect.StructField) *string {
+	if comments == nil {
+		return nil
+	}
+	if comment, ok := comments.FieldComment(container, field.Name); ok {
+		return &comment
+	}
+	return nil
+}
+
+// A source of doc comments and constants, typically extracted from the
+// Go sources by package `extract`.
+type Comments interface {
+	// The doc comment of a named type, if any.
+	TypeComment(typ reflect.Type) (string, bool)
+
+	// The doc comment of a field of a struct, if any.
+	FieldComment(container reflect.Type, field string) (string, bool)
+
+	// The constants enumerating the values of a named type, in order of declaration.
+	Constants(typ reflect.Type) []Constant
+}
+
+// A constant of a named type, e.g. `const StatusActive Status = "active"`.
+type Constant struct {
+	// The name of the constant, e.g. "StatusActive".
+	Name string
+
+	// The value of the constant, e.g. "active".
+	Value shared.Json
+
+	// The doc comment of the constant, if any.
+	Comment *string
+}
+
+// Utility: get the constants declared for a named type, if `comments` is not nil.
+func GetConstants(comments Comments, typ reflect.Type) []Constant {
+	if comments == nil {
+		return nil
+	}
+	return comments.Constants(typ)
+}
+
 // Utility: get the description from a HasDescription.
 func GetExternalDocs(typ reflect.Type) *External {
 	phony := reflect.New(typ)
@@ -63,3 +129,14 @@ func GetExternalDocs(typ reflect.Type) *External {
 	}
 	return nil
 }
+
+// The note added to the description of fields initialized by tag `orMethod`.
+const MethodNote = "If unspecified, a default value is computed by the server."
+
+// Utility: append MethodNote to a description.
+func WithMethodNote(description *string) *string {
+	if description == nil {
+		return shared.Ptr(MethodNote)
+	}
+	return shared.Ptr(fmt.Sprint(*description, "\n\n", MethodNote))
+}
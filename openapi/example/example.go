@@ -14,10 +14,17 @@ type Example interface {
 
 // https://spec.openapis.org/oas/v3.0.1.html#example-object
 type Spec struct {
-	Summary       string
-	Description   string
-	Value         *shared.Json
-	ExternalValue *string
+	// Short description for the example.
+	Summary string `json:"summary,omitempty"`
+
+	// Long description for the example. May include Markdown.
+	Description string `json:"description,omitempty"`
+
+	// Embedded literal example. Mutually exclusive with ExternalValue.
+	Value *shared.Json `json:"value,omitempty"`
+
+	// A URL that points to the literal example. Mutually exclusive with Value.
+	ExternalValue *string `json:"externalValue,omitempty"`
 }
 
 func (Spec) sealed() {}
@@ -0,0 +1,282 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi/callback"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
+	"github.com/pasqal-io/gousset/openapi/example"
+	"github.com/pasqal-io/gousset/openapi/header"
+	"github.com/pasqal-io/gousset/openapi/media"
+	"github.com/pasqal-io/gousset/openapi/operation"
+	"github.com/pasqal-io/gousset/openapi/parameter"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/request"
+	"github.com/pasqal-io/gousset/openapi/response"
+	"github.com/pasqal-io/gousset/openapi/schema"
+	"github.com/pasqal-io/gousset/shared"
+)
+
+// An example that does not conform to the schema it decorates.
+type ExampleMismatch struct {
+	// A JSON pointer to the example within the spec, e.g.
+	// `/paths/~1users/get/responses/200/content/application~1json/example`.
+	Location string
+
+	// The violations of the schema by the example.
+	Violations []schema.ValidationError
+}
+
+func (m ExampleMismatch) Error() string {
+	messages := make([]string, 0, len(m.Violations))
+	for _, violation := range m.Violations {
+		messages = append(messages, violation.Error())
+	}
+	return fmt.Sprintf("example at %s does not conform to its schema: %s", m.Location, strings.Join(messages, "; "))
+}
+
+// Walk through a spec, checking every example against its schema.
+type exampleLinter struct {
+	definitions map[string]schema.Schema
+	mismatches  []ExampleMismatch
+}
+
+// Extend a JSON pointer with segments.
+func appendPointer(pointer string, segments ...string) string {
+	builder := strings.Builder{}
+	builder.WriteString(pointer)
+	for _, segment := range segments {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
+
+// Return the keys of a map, sorted, to make reports reproducible.
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (l *exampleLinter) check(s schema.Schema, value *shared.Json, location string) {
+	if s == nil || value == nil {
+		return
+	}
+	violations := schema.ValidateWithDefinitions(s, *value, l.definitions)
+	if len(violations) != 0 {
+		l.mismatches = append(l.mismatches, ExampleMismatch{
+			Location:   location,
+			Violations: violations,
+		})
+	}
+}
+
+func (l *exampleLinter) checkExamples(s schema.Schema, examples map[string]example.Example, location string) {
+	for _, name := range sortedKeys(examples) {
+		if spec, ok := examples[name].(example.Spec); ok {
+			l.check(s, spec.Value, appendPointer(location, name, "value"))
+		}
+	}
+}
+
+// Check the examples embedded in a schema and its children.
+func (l *exampleLinter) lintSchema(s schema.Schema, location string) {
+	var share *schema.Shared
+	switch typed := s.(type) {
+	case schema.Primitive:
+		share = &typed.Shared
+	case schema.Array:
+		share = &typed.Shared
+		l.lintSchema(typed.Items, appendPointer(location, "items"))
+	case schema.Object:
+		share = &typed.Shared
+		for _, name := range sortedKeys(typed.Properties) {
+			l.lintSchema(typed.Properties[name], appendPointer(location, "properties", name))
+		}
+		if typed.AdditionalProperties != nil {
+			l.lintSchema(*typed.AdditionalProperties, appendPointer(location, "additionalProperties"))
+		}
+	case schema.OneOf:
+		for i, variant := range typed.OneOf {
+			l.lintSchema(variant, appendPointer(location, "oneOf", fmt.Sprint(i)))
+		}
+	case schema.AllOf:
+		for i, variant := range typed.AllOf {
+			l.lintSchema(variant, appendPointer(location, "allOf", fmt.Sprint(i)))
+		}
+	}
+	if share != nil {
+		l.check(s, share.Example, appendPointer(location, "example"))
+	}
+}
+
+func (l *exampleLinter) lintMediaTypes(content map[string]media.Type, location string) {
+	for _, name := range sortedKeys(content) {
+		mediaType := content[name]
+		here := appendPointer(location, name)
+		if mediaType.Schema == nil {
+			continue
+		}
+		l.lintSchema(*mediaType.Schema, appendPointer(here, "schema"))
+		l.check(*mediaType.Schema, mediaType.Example, appendPointer(here, "example"))
+		if mediaType.Examples != nil {
+			l.checkExamples(*mediaType.Schema, *mediaType.Examples, appendPointer(here, "examples"))
+		}
+	}
+}
+
+func (l *exampleLinter) lintParameters(params []parameter.Parameter, location string) {
+	for i, param := range params {
+		l.lintParameter(param, appendPointer(location, fmt.Sprint(i)))
+	}
+}
+
+func (l *exampleLinter) lintParameter(param parameter.Parameter, here string) {
+	spec, ok := param.(parameter.Spec)
+	if !ok {
+		// References are checked with the components.
+		return
+	}
+	if spec.SchemaSpec != nil {
+		l.lintSchema(spec.SchemaSpec.Schema, appendPointer(here, "schema"))
+		l.check(spec.SchemaSpec.Schema, spec.SchemaSpec.Example, appendPointer(here, "example"))
+		if spec.SchemaSpec.Examples != nil {
+			for j, ex := range *spec.SchemaSpec.Examples {
+				if exSpec, ok := ex.(example.Spec); ok {
+					l.check(spec.SchemaSpec.Schema, exSpec.Value, appendPointer(here, "examples", fmt.Sprint(j), "value"))
+				}
+			}
+		}
+	}
+	if spec.ContentSpec != nil {
+		l.lintMediaTypes(spec.ContentSpec.Content, appendPointer(here, "content"))
+	}
+}
+
+func (l *exampleLinter) lintResponse(resp response.Response, location string) {
+	spec, ok := resp.(response.Spec)
+	if !ok {
+		return
+	}
+	if spec.Headers != nil {
+		for _, name := range sortedKeys(*spec.Headers) {
+			headerSpec, ok := (*spec.Headers)[name].(header.Spec)
+			if !ok {
+				continue
+			}
+			here := appendPointer(location, "headers", name)
+			if headerSpec.SchemaSpec != nil {
+				l.lintSchema(headerSpec.SchemaSpec.Schema, appendPointer(here, "schema"))
+				l.check(headerSpec.SchemaSpec.Schema, headerSpec.SchemaSpec.Example, appendPointer(here, "example"))
+			}
+			if headerSpec.ContentSpec != nil {
+				l.lintMediaTypes(headerSpec.ContentSpec.Content, appendPointer(here, "content"))
+			}
+		}
+	}
+	if spec.Content != nil {
+		l.lintMediaTypes(*spec.Content, appendPointer(location, "content"))
+	}
+}
+
+func (l *exampleLinter) lintOperation(op *operation.Spec, location string) {
+	l.lintParameters(op.Parameters, appendPointer(location, "parameters"))
+	if op.Request != nil {
+		if spec, ok := (*op.Request).(request.Spec); ok {
+			l.lintMediaTypes(spec.Content, appendPointer(location, "requestBody", "content"))
+		}
+	}
+	l.lintResponse(op.Responses.Default, appendPointer(location, "responses", "default"))
+	if op.Responses.PerCode != nil {
+		codes := make([]uint16, 0, len(*op.Responses.PerCode))
+		for code := range *op.Responses.PerCode {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			l.lintResponse((*op.Responses.PerCode)[code], appendPointer(location, "responses", fmt.Sprint(code)))
+		}
+	}
+	if op.Callbacks != nil {
+		for _, name := range sortedKeys(*op.Callbacks) {
+			spec, ok := (*op.Callbacks)[name].(callback.Spec)
+			if !ok {
+				continue
+			}
+			for _, template := range sortedKeys(spec) {
+				if item, ok := spec[template].(path.Spec); ok {
+					l.lintPath(item, appendPointer(location, "callbacks", name, template))
+				}
+			}
+		}
+	}
+}
+
+func (l *exampleLinter) lintPath(spec path.Spec, location string) {
+	if spec.Parameters != nil {
+		l.lintParameters(*spec.Parameters, appendPointer(location, "parameters"))
+	}
+	operations := spec.Operations()
+	for _, verb := range sortedKeys(operations) {
+		l.lintOperation(operations[verb], appendPointer(location, string(verb)))
+	}
+}
+
+// Check every example of the spec against the schema it decorates.
+func lintExamples(spec Spec) []ExampleMismatch {
+	l := exampleLinter{}
+	if spec.Components.Schemas != nil {
+		l.definitions = *spec.Components.Schemas
+		for _, name := range sortedKeys(l.definitions) {
+			l.lintSchema(l.definitions[name], appendPointer("/components/schemas", name))
+		}
+	}
+	if spec.Components.Parameters != nil {
+		for _, name := range sortedKeys(*spec.Components.Parameters) {
+			l.lintParameter((*spec.Components.Parameters)[name], appendPointer("/components/parameters", name))
+		}
+	}
+	for _, route := range sortedKeys(spec.Paths) {
+		l.lintPath(spec.Paths[route], appendPointer("/paths", string(route)))
+	}
+	for _, name := range sortedKeys(spec.Webhooks) {
+		l.lintPath(spec.Webhooks[name], appendPointer("/webhooks", name))
+	}
+	return l.mismatches
+}
+
+// Check every example, reporting mismatches as warnings or, in strict mode,
+// as errors.
+func checkExamples(spec Spec, strict bool, report *diagnostic.Report) error {
+	mismatches := lintExamples(spec)
+	if len(mismatches) == 0 {
+		return nil
+	}
+	if !strict {
+		for _, mismatch := range mismatches {
+			messages := make([]string, 0, len(mismatch.Violations))
+			for _, violation := range mismatch.Violations {
+				messages = append(messages, violation.Error())
+			}
+			report.Add(diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityWarning,
+				Code:     diagnostic.CodeExampleMismatch,
+				Message:  fmt.Sprintf("example does not conform to its schema: %s", strings.Join(messages, "; ")),
+				Location: mismatch.Location,
+			})
+		}
+		return nil
+	}
+	errs := make([]error, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		errs = append(errs, mismatch)
+	}
+	return fmt.Errorf("invalid examples: %w", errors.Join(errs...))
+}
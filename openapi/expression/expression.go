@@ -0,0 +1,243 @@
+// Runtime expressions, used e.g. by links and callbacks to refer
+// to values available in the HTTP request or response.
+//
+// https://spec.openapis.org/oas/v3.0.1.html#runtime-expressions
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A runtime expression, e.g. `$request.path.id` or `$response.body#/id`.
+//
+// Use the builders `Url`, `Method`, `StatusCode`, `Request*` and `Response*`
+// rather than writing expressions by hand.
+type Expression string
+
+// The URL of the request, i.e. `$url`.
+func Url() Expression {
+	return Expression("$url")
+}
+
+// The HTTP method of the request, i.e. `$method`.
+func Method() Expression {
+	return Expression("$method")
+}
+
+// The status code of the response, i.e. `$statusCode`.
+func StatusCode() Expression {
+	return Expression("$statusCode")
+}
+
+// A parameter passed in the path of the request, e.g. `$request.path.id`.
+func RequestPath(name string) Expression {
+	return Expression(fmt.Sprint("$request.path.", name))
+}
+
+// A parameter passed in the query of the request, e.g. `$request.query.page`.
+func RequestQuery(name string) Expression {
+	return Expression(fmt.Sprint("$request.query.", name))
+}
+
+// A header of the request, e.g. `$request.header.X-Request-Id`.
+func RequestHeader(name string) Expression {
+	return Expression(fmt.Sprint("$request.header.", name))
+}
+
+// The body of the request or a value within the body, e.g.
+// `RequestBody("user", "id")` is `$request.body#/user/id`.
+func RequestBody(pointer ...string) Expression {
+	return Expression(fmt.Sprint("$request.body", makePointer(pointer)))
+}
+
+// A header of the response, e.g. `$response.header.Location`.
+func ResponseHeader(name string) Expression {
+	return Expression(fmt.Sprint("$response.header.", name))
+}
+
+// The body of the response or a value within the body, e.g.
+// `ResponseBody("id")` is `$response.body#/id`.
+func ResponseBody(pointer ...string) Expression {
+	return Expression(fmt.Sprint("$response.body", makePointer(pointer)))
+}
+
+// Encode a list of segments as a JSON pointer fragment, e.g. `#/user/id`.
+func makePointer(segments []string) string {
+	if len(segments) == 0 {
+		return ""
+	}
+	builder := strings.Builder{}
+	builder.WriteString("#")
+	for _, segment := range segments {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
+
+// The part of the exchange referenced by an expression.
+type Kind string
+
+const (
+	KindUrl        = Kind("$url")
+	KindMethod     = Kind("$method")
+	KindStatusCode = Kind("$statusCode")
+	KindRequest    = Kind("$request")
+	KindResponse   = Kind("$response")
+)
+
+// Where in the request or response a value is read.
+type Source string
+
+const (
+	SourceHeader = Source("header")
+	SourceQuery  = Source("query")
+	SourcePath   = Source("path")
+	SourceBody   = Source("body")
+)
+
+// A runtime expression, decomposed.
+type Parsed struct {
+	// Which part of the exchange this expression refers to.
+	Kind Kind
+
+	// Where the value is read. Empty unless Kind is KindRequest or KindResponse.
+	Source Source
+
+	// The name of the header, query or path parameter. Empty for other sources.
+	Name string
+
+	// For SourceBody, the segments of the JSON pointer within the body, unescaped.
+	// Empty if the expression refers to the entire body.
+	Pointer []string
+}
+
+// Decompose an expression, checking that it is well-formed.
+func (e Expression) Parse() (Parsed, error) {
+	raw := string(e)
+	switch Kind(raw) {
+	case KindUrl, KindMethod, KindStatusCode:
+		return Parsed{Kind: Kind(raw)}, nil
+	}
+	var result Parsed
+	var rest string
+	if after, ok := strings.CutPrefix(raw, string(KindRequest)+"."); ok {
+		result.Kind = KindRequest
+		rest = after
+	} else if after, ok := strings.CutPrefix(raw, string(KindResponse)+"."); ok {
+		result.Kind = KindResponse
+		rest = after
+	} else {
+		return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", expected $url, $method, $statusCode, $request.* or $response.*", raw)
+	}
+
+	if after, ok := strings.CutPrefix(rest, string(SourceBody)); ok {
+		result.Source = SourceBody
+		if after == "" {
+			return result, nil
+		}
+		pointer, ok := strings.CutPrefix(after, "#")
+		if !ok {
+			return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", expected a JSON pointer introduced by '#' after body", raw)
+		}
+		if pointer == "" {
+			return result, nil
+		}
+		if !strings.HasPrefix(pointer, "/") {
+			return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", JSON pointer must start with '/'", raw)
+		}
+		for _, segment := range strings.Split(pointer[1:], "/") {
+			result.Pointer = append(result.Pointer, strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~"))
+		}
+		return result, nil
+	}
+
+	source, name, ok := strings.Cut(rest, ".")
+	if !ok || name == "" {
+		return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", expected header.<name>, query.<name>, path.<name> or body", raw)
+	}
+	switch Source(source) {
+	case SourceHeader:
+		for _, c := range name {
+			if !isTokenChar(c) {
+				return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", invalid character %q in header name", raw, c)
+			}
+		}
+	case SourceQuery, SourcePath:
+		if source == string(SourcePath) && result.Kind == KindResponse {
+			return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", responses do not have path parameters", raw)
+		}
+		if source == string(SourceQuery) && result.Kind == KindResponse {
+			return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", responses do not have query parameters", raw)
+		}
+	default:
+		return Parsed{}, fmt.Errorf("invalid runtime expression \"%s\", unknown source \"%s\"", raw, source)
+	}
+	result.Source = Source(source)
+	result.Name = name
+	return result, nil
+}
+
+// Check that an expression is well-formed.
+func (e Expression) Validate() error {
+	_, err := e.Parse()
+	return err
+}
+
+// The runtime expression held by a value, e.g. the value of a link parameter.
+//
+// This is either an `Expression` or a string starting with `$`, e.g. `"$request.path.id"`.
+// Other values are constants.
+func FromValue(value any) (Expression, bool) {
+	switch typed := value.(type) {
+	case Expression:
+		return typed, true
+	case string:
+		if strings.HasPrefix(typed, "$") {
+			return Expression(typed), true
+		}
+	}
+	return "", false
+}
+
+// Per RFC 7230, the characters that may appear in a header name.
+func isTokenChar(c rune) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
+
+// Embed an expression in a string template, e.g.
+//
+//	fmt.Sprint("https://example.org/notify?id=", expression.Embed(expression.RequestBody("id")))
+//
+// is `https://example.org/notify?id={$request.body#/id}`.
+func Embed(e Expression) string {
+	return fmt.Sprint("{", e, "}")
+}
+
+// Check that a string is either a runtime expression or a template
+// containing only well-formed runtime expressions between braces,
+// e.g. `{$request.body#/callbackUrl}/done`.
+func ValidateTemplate(template string) error {
+	if strings.HasPrefix(template, "$") {
+		return Expression(template).Validate()
+	}
+	rest := template
+	for {
+		start := strings.Index(rest, "{")
+		if start == -1 {
+			return nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end == -1 {
+			return fmt.Errorf("invalid template \"%s\", unclosed '{'", template)
+		}
+		if err := Expression(rest[start+1 : start+end]).Validate(); err != nil {
+			return fmt.Errorf("invalid template \"%s\": %w", template, err)
+		}
+		rest = rest[start+end+1:]
+	}
+}
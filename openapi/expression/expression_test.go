@@ -0,0 +1,44 @@
+package expression_test
+
+import (
+	"testing"
+
+	"github.com/pasqal-io/gousset/openapi/expression"
+	"gotest.tools/assert"
+)
+
+// Test that builders produce well-formed expressions.
+func TestBuilders(t *testing.T) {
+	assert.Equal(t, string(expression.Url()), "$url")
+	assert.Equal(t, string(expression.RequestPath("id")), "$request.path.id")
+	assert.Equal(t, string(expression.RequestHeader("X-Request-Id")), "$request.header.X-Request-Id")
+	assert.Equal(t, string(expression.RequestBody()), "$request.body")
+	assert.Equal(t, string(expression.ResponseBody("user", "a/b~c")), "$response.body#/user/a~1b~0c")
+}
+
+// Test that parsing reverses the builders.
+func TestParse(t *testing.T) {
+	parsed, err := expression.ResponseBody("user", "a/b~c").Parse()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed, expression.Parsed{
+		Kind:    expression.KindResponse,
+		Source:  expression.SourceBody,
+		Pointer: []string{"user", "a/b~c"},
+	})
+
+	parsed, err = expression.RequestQuery("page").Parse()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed, expression.Parsed{
+		Kind:   expression.KindRequest,
+		Source: expression.SourceQuery,
+		Name:   "page",
+	})
+}
+
+// Test that ill-formed expressions are rejected.
+func TestParseInvalid(t *testing.T) {
+	for _, invalid := range []string{"", "$foo", "$request", "$request.path", "$request.cookie.id", "$response.path.id", "$request.body/id", "$request.header.a b"} {
+		_, err := expression.Expression(invalid).Parse()
+		assert.Assert(t, err != nil, "expected an error for %s", invalid)
+	}
+}
@@ -3,8 +3,12 @@ package header
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
+	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
 	"github.com/pasqal-io/gousset/openapi/media"
 	"github.com/pasqal-io/gousset/openapi/schema"
@@ -64,16 +68,42 @@ type Implementation struct {
 	Deprecated  bool
 	SchemaSpec  *SchemaImplementation
 	ContentSpec *ContentImplementation
+
+	// Where to report diagnostics. If nil, they are logged.
+	//
+	// Inherited by `SchemaSpec` and `ContentSpec` unless they specify their own.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	//
+	// Inherited by `SchemaSpec` and `ContentSpec` unless they specify their own.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
 type SchemaImplementation struct {
 	Type     reflect.Type
 	Example  *shared.Json
 	Examples *[]example.Example
+
+	// Where to report diagnostics. If nil, they are logged.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
 type ContentImplementation struct {
 	Content map[string]media.Implementation
+
+	// Where to report diagnostics. If nil, they are logged.
+	//
+	// Inherited by each media type unless it specifies its own.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	//
+	// Inherited by each media type unless it specifies its own.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
 func FromImplementation(impl Implementation) (Header, error) {
@@ -82,21 +112,38 @@ func FromImplementation(impl Implementation) (Header, error) {
 		Required:    impl.Required,
 		Deprecated:  impl.Deprecated,
 	}
+	var errs []error
 	if impl.SchemaSpec != nil {
-		schema, err := FromSchemaImplementation(*impl.SchemaSpec)
+		schemaImpl := *impl.SchemaSpec
+		if schemaImpl.Diagnostics == nil {
+			schemaImpl.Diagnostics = impl.Diagnostics
+		}
+		if schemaImpl.Comments == nil {
+			schemaImpl.Comments = impl.Comments
+		}
+		schema, err := FromSchemaImplementation(schemaImpl)
 		if err != nil {
-			return result, fmt.Errorf("while compiling header, error in schema: %w", err)
+			errs = append(errs, diagnostic.Wrap(err, "while compiling header, error in schema"))
+		} else {
+			result.SchemaSpec = &schema
 		}
-		result.SchemaSpec = &schema
 	}
 	if impl.ContentSpec != nil {
-		content, err := FromContentImplementation(*impl.ContentSpec)
+		contentImpl := *impl.ContentSpec
+		if contentImpl.Diagnostics == nil {
+			contentImpl.Diagnostics = impl.Diagnostics
+		}
+		if contentImpl.Comments == nil {
+			contentImpl.Comments = impl.Comments
+		}
+		content, err := FromContentImplementation(contentImpl)
 		if err != nil {
-			return result, fmt.Errorf("while compiling header, error in content: %w", err)
+			errs = append(errs, diagnostic.Wrap(err, "while compiling header, error in content"))
+		} else {
+			result.ContentSpec = &content
 		}
-		result.ContentSpec = &content
 	}
-	return result, nil
+	return result, diagnostic.Join(errs...)
 }
 
 func FromSchemaImplementation(impl SchemaImplementation) (SchemaSpec, error) {
@@ -105,9 +152,9 @@ func FromSchemaImplementation(impl SchemaImplementation) (SchemaSpec, error) {
 		Example:  impl.Example,
 		Examples: impl.Examples,
 	}
-	schema, err := schema.FromImplementation(schema.Implementation{Type: impl.Type, PublicNameKey: "header"})
+	schema, err := schema.FromImplementation(schema.Implementation{Type: impl.Type, PublicNameKey: "header", Diagnostics: impl.Diagnostics, Comments: impl.Comments})
 	if err != nil {
-		return result, fmt.Errorf("while compiling schema, error: %w", err)
+		return result, diagnostic.Wrap(err, "while compiling schema, error")
 	}
 	result.Schema = schema
 	return result, nil
@@ -117,12 +164,22 @@ func FromContentImplementation(impl ContentImplementation) (ContentSpec, error)
 	result := ContentSpec{
 		Content: map[string]media.Type{},
 	}
-	for k, v := range impl.Content {
+	var errs []error
+	// Sorted, to make errors reproducible.
+	for _, k := range slices.Sorted(maps.Keys(impl.Content)) {
+		v := impl.Content[k]
+		if v.Diagnostics == nil {
+			v.Diagnostics = impl.Diagnostics
+		}
+		if v.Comments == nil {
+			v.Comments = impl.Comments
+		}
 		content, err := media.FromImplementation(v)
 		if err != nil {
-			return result, fmt.Errorf("while compiling content type %s, error: %w", k, err)
+			errs = append(errs, diagnostic.Wrap(err, fmt.Sprintf("while compiling content type %s, error", k)))
+			continue
 		}
 		result.Content[k] = content
 	}
-	return result, nil
+	return result, diagnostic.Join(errs...)
 }
@@ -4,6 +4,7 @@ package hooks
 import (
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
+	"github.com/pasqal-io/gousset/openapi/parameter"
 	"github.com/pasqal-io/gousset/openapi/schema"
 )
 
@@ -22,6 +23,9 @@ type HasExample = example.HasExample
 // Implement this to add a examples with comments to all instances of a type.
 type HasExamples = example.HasExamples
 
+// Implement this to provide a default value for all instances of a type.
+type HasDefault = schema.HasDefault
+
 // Implement this to override the schema that gousset infers for a type.
 type HasSchema = schema.HasSchema
 
@@ -54,3 +58,6 @@ type HasMin = schema.HasMin
 
 // Implement this to mark a maximal value for a number.
 type HasMax = schema.HasMax
+
+// Implement this on the type of a parameter to document it once, in `components.parameters`.
+type IsParameterComponent = parameter.IsComponent
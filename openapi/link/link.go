@@ -1,6 +1,17 @@
+// Links between operations, e.g. from the response of "create order"
+// to the operation "get order".
 package link
 
-import "github.com/pasqal-io/gousset/shared"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/pasqal-io/gousset/openapi/expression"
+	"github.com/pasqal-io/gousset/openapi/server"
+	"github.com/pasqal-io/gousset/shared"
+)
 
 type Link interface {
 	sealed()
@@ -8,8 +19,27 @@ type Link interface {
 
 // https://spec.openapis.org/oas/v3.0.1.html#link-object
 type Spec struct {
-	OperationRef string
-	// ... Other fields to be added as needed
+	// A relative or absolute reference to an OAS operation, e.g. `#/paths/~1orders~1{id}/get`.
+	//
+	// Mutually exclusive with OperationId.
+	OperationRef *string `json:"operationRef,omitempty"`
+
+	// The name of an existing, resolvable OAS operation, as defined with a unique operationId.
+	//
+	// Mutually exclusive with OperationRef.
+	OperationId *string `json:"operationId,omitempty"`
+
+	// A map representing parameters to pass to an operation as specified with operationId or identified via operationRef. The key is the parameter name to be used, whereas the value can be a constant or an expression to be evaluated and passed to the linked operation. The parameter name can be qualified using the parameter location [{in}.]{name} for operations that use the same parameter name in different locations (e.g. path.id).
+	Parameters *map[string]shared.Json `json:"parameters,omitempty"`
+
+	// A literal value or {expression} to use as a request body when calling the target operation.
+	RequestBody *shared.Json `json:"requestBody,omitempty"`
+
+	// A description of the link. May include Markdown.
+	Description *string `json:"description,omitempty"`
+
+	// A server object to be used by the target operation.
+	Server *server.Server `json:"server,omitempty"`
 }
 
 func (Spec) sealed() {}
@@ -25,14 +55,65 @@ func Ref(to string) Reference {
 
 func (Reference) sealed() {}
 
+var _ Link = Reference{}
+
 // User-provided metadata containing information on the implementation
 // to be converted to OpenAPI spec.
 type Implementation struct {
-	OperationRef string
+	// A reference to the target operation, e.g. `#/paths/~1orders~1{id}/get`.
+	//
+	// Exactly one of OperationRef and OperationId MUST be provided.
+	OperationRef *string
+
+	// The operationId of the target operation.
+	//
+	// Exactly one of OperationRef and OperationId MUST be provided.
+	OperationId *string
+
+	// Parameters to pass to the target operation.
+	//
+	// Values may be constants or runtime expressions built with
+	// package `expression`, e.g. `expression.ResponseBody("id")`.
+	Parameters *map[string]shared.Json
+
+	// The body to pass to the target operation, either a constant
+	// or a runtime expression.
+	RequestBody *shared.Json
+
+	Description *string
+	Server      *server.Server
 }
 
 func FromImplementation(impl Implementation) (Link, error) {
-	result := Spec{}
-	result.OperationRef = impl.OperationRef
-	return result, nil
+	if impl.OperationRef == nil && impl.OperationId == nil {
+		return Spec{}, errors.New("while compiling link, expected either an OperationRef or an OperationId")
+	}
+	if impl.OperationRef != nil && impl.OperationId != nil {
+		return Spec{}, errors.New("while compiling link, OperationRef and OperationId are mutually exclusive")
+	}
+	if impl.Parameters != nil {
+		// Sorted, to make errors reproducible.
+		for _, k := range slices.Sorted(maps.Keys(*impl.Parameters)) {
+			if expr, ok := expression.FromValue((*impl.Parameters)[k]); ok {
+				if err := expr.Validate(); err != nil {
+					return Spec{}, fmt.Errorf("while compiling link, invalid value for parameter %s: %w", k, err)
+				}
+			}
+		}
+	}
+	if impl.RequestBody != nil {
+		if expr, ok := expression.FromValue(*impl.RequestBody); ok {
+			if err := expr.Validate(); err != nil {
+				return Spec{}, fmt.Errorf("while compiling link, invalid request body: %w", err)
+			}
+		}
+	}
+	return Spec{
+		OperationRef: impl.OperationRef,
+		OperationId:  impl.OperationId,
+		Parameters:   impl.Parameters,
+		RequestBody:  impl.RequestBody,
+		Description:  impl.Description,
+		Server:       impl.Server,
+	}, nil
 }
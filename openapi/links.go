@@ -0,0 +1,236 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi/expression"
+	"github.com/pasqal-io/gousset/openapi/link"
+	"github.com/pasqal-io/gousset/openapi/operation"
+	"github.com/pasqal-io/gousset/openapi/parameter"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/response"
+)
+
+// An operation, along with its location in the spec.
+type located struct {
+	route     path.Route
+	verb      path.Verb
+	pathSpec  path.Spec
+	operation *operation.Spec
+}
+
+// The parameters applicable to this operation, including those
+// declared at path level.
+func (l located) parameters() []parameter.Spec {
+	var result []parameter.Spec
+	var all []parameter.Parameter
+	if l.pathSpec.Parameters != nil {
+		all = append(all, *l.pathSpec.Parameters...)
+	}
+	all = append(all, l.operation.Parameters...)
+	for _, param := range all {
+		if spec, ok := parameter.Resolve(param, nil); ok {
+			result = append(result, spec)
+		}
+	}
+	return result
+}
+
+// Find a parameter by name, optionally qualified by its location, e.g. `path.id`.
+func (l located) hasParameter(name string, in *parameter.In) bool {
+	for _, param := range l.parameters() {
+		if in != nil && param.In != *in {
+			continue
+		}
+		if param.In == parameter.InHeader {
+			if strings.EqualFold(param.Name, name) {
+				return true
+			}
+		} else if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// List all the operations of a spec, in a stable order.
+func allOperations(spec Spec) []located {
+	var routes []path.Route
+	for route := range spec.Paths {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+	var result []located
+	for _, route := range routes {
+		pathSpec := spec.Paths[route]
+		operations := pathSpec.Operations()
+		var verbs []path.Verb
+		for verb := range operations {
+			verbs = append(verbs, verb)
+		}
+		slices.Sort(verbs)
+		for _, verb := range verbs {
+			result = append(result, located{
+				route:     route,
+				verb:      verb,
+				pathSpec:  pathSpec,
+				operation: operations[verb],
+			})
+		}
+	}
+	return result
+}
+
+// Find the operation targeted by a link.
+func findLinkTarget(operations []located, spec link.Spec) (located, error) {
+	if spec.OperationId != nil {
+		for _, op := range operations {
+			if op.operation.OperationId == *spec.OperationId {
+				return op, nil
+			}
+		}
+		return located{}, fmt.Errorf("unknown operationId \"%s\"", *spec.OperationId)
+	}
+	if spec.OperationRef == nil {
+		return located{}, fmt.Errorf("link has neither operationId nor operationRef")
+	}
+	pointer, ok := strings.CutPrefix(*spec.OperationRef, "#/paths/")
+	if !ok {
+		return located{}, fmt.Errorf("cannot resolve operationRef \"%s\", only references to #/paths/ within this spec are supported", *spec.OperationRef)
+	}
+	encodedRoute, verb, ok := strings.Cut(pointer, "/")
+	if !ok {
+		return located{}, fmt.Errorf("invalid operationRef \"%s\", expected #/paths/<route>/<verb>", *spec.OperationRef)
+	}
+	// The route may be percent-encoded, e.g. `~1users~1%7Bid%7D`.
+	unescaped, err := url.PathUnescape(encodedRoute)
+	if err != nil {
+		return located{}, fmt.Errorf("invalid operationRef \"%s\": %w", *spec.OperationRef, err)
+	}
+	route := path.Route(strings.ReplaceAll(strings.ReplaceAll(unescaped, "~1", "/"), "~0", "~"))
+	for _, op := range operations {
+		if op.route == route && string(op.verb) == verb {
+			return op, nil
+		}
+	}
+	return located{}, fmt.Errorf("unknown operationRef \"%s\"", *spec.OperationRef)
+}
+
+// Check that a runtime expression only refers to values that exist in the
+// source operation.
+func checkExpression(source located, resp response.Spec, expr expression.Expression) error {
+	parsed, err := expr.Parse()
+	if err != nil {
+		return err
+	}
+	switch parsed.Kind {
+	case expression.KindRequest:
+		var in parameter.In
+		switch parsed.Source {
+		case expression.SourcePath:
+			in = parameter.InPath
+		case expression.SourceQuery:
+			in = parameter.InQuery
+		case expression.SourceHeader:
+			in = parameter.InHeader
+		case expression.SourceBody:
+			if source.operation.Request == nil {
+				return fmt.Errorf("expression %s refers to the request body, but operation %s does not accept a body", expr, source.operation.OperationId)
+			}
+			return nil
+		}
+		if !source.hasParameter(parsed.Name, &in) {
+			return fmt.Errorf("expression %s refers to an unknown %s parameter of operation %s", expr, in, source.operation.OperationId)
+		}
+	case expression.KindResponse:
+		if parsed.Source == expression.SourceHeader {
+			if resp.Headers != nil {
+				for name := range *resp.Headers {
+					if strings.EqualFold(name, parsed.Name) {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("expression %s refers to a header that is not declared by the response", expr)
+		}
+		if parsed.Source == expression.SourceBody && resp.Content == nil {
+			return fmt.Errorf("expression %s refers to the response body, but the response has no content", expr)
+		}
+	}
+	return nil
+}
+
+// Check a single link, declared in response `resp` of operation `source`.
+func checkLink(operations []located, source located, resp response.Spec, spec link.Spec) error {
+	target, err := findLinkTarget(operations, spec)
+	if err != nil {
+		return err
+	}
+	if spec.Parameters != nil {
+		for _, name := range sortedKeys(*spec.Parameters) {
+			value := (*spec.Parameters)[name]
+			var in *parameter.In
+			if prefix, suffix, ok := strings.Cut(name, "."); ok {
+				switch parameter.In(prefix) {
+				case parameter.InPath, parameter.InQuery, parameter.InHeader, parameter.InCookie:
+					in = (*parameter.In)(&prefix)
+					name = suffix
+				}
+			}
+			if !target.hasParameter(name, in) {
+				return fmt.Errorf("parameter %s is not a parameter of target operation %s", name, target.operation.OperationId)
+			}
+			if expr, ok := expression.FromValue(value); ok {
+				if err := checkExpression(source, resp, expr); err != nil {
+					return fmt.Errorf("in parameter %s: %w", name, err)
+				}
+			}
+		}
+	}
+	if spec.RequestBody != nil {
+		if target.operation.Request == nil {
+			return fmt.Errorf("link provides a request body, but target operation %s does not accept a body", target.operation.OperationId)
+		}
+		if expr, ok := expression.FromValue(*spec.RequestBody); ok {
+			if err := checkExpression(source, resp, expr); err != nil {
+				return fmt.Errorf("in request body: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check that all the links declared in responses refer to operations
+// and parameters that exist in the spec.
+func validateLinks(spec Spec) error {
+	operations := allOperations(spec)
+	for _, source := range operations {
+		responses := map[string]response.Response{
+			"default": source.operation.Responses.Default,
+		}
+		if source.operation.Responses.PerCode != nil {
+			for code, resp := range *source.operation.Responses.PerCode {
+				responses[fmt.Sprint(code)] = resp
+			}
+		}
+		for _, code := range sortedKeys(responses) {
+			respSpec, ok := responses[code].(response.Spec)
+			if !ok || respSpec.Links == nil {
+				continue
+			}
+			for _, name := range sortedKeys(*respSpec.Links) {
+				linkSpec, ok := (*respSpec.Links)[name].(link.Spec)
+				if !ok {
+					continue
+				}
+				if err := checkLink(operations, source, respSpec, linkSpec); err != nil {
+					return fmt.Errorf("invalid link %s in response %s of %s %s: %w", name, code, source.verb, source.route, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
+	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
 	"github.com/pasqal-io/gousset/openapi/schema"
 	"github.com/pasqal-io/gousset/shared"
@@ -36,6 +38,12 @@ type Implementation struct {
 	Example       *shared.Json
 	Examples      *map[string]example.Example
 	PublicNameKey string
+
+	// Where to report diagnostics. If nil, they are logged.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
 func FromImplementation(impl Implementation) (Type, error) {
@@ -48,9 +56,9 @@ func FromImplementation(impl Implementation) (Type, error) {
 		impl.PublicNameKey = "json"
 	}
 	if impl.Type.Kind() != reflect.Invalid {
-		typ, err := schema.FromImplementation(schema.Implementation{Type: impl.Type, PublicNameKey: impl.PublicNameKey})
+		typ, err := schema.FromImplementation(schema.Implementation{Type: impl.Type, PublicNameKey: impl.PublicNameKey, Diagnostics: impl.Diagnostics, Comments: impl.Comments})
 		if err != nil {
-			return result, fmt.Errorf("while collecting media type, error: %w", err)
+			return result, diagnostic.Wrap(err, "while collecting media type, error")
 		}
 		result.Schema = &typ
 	}
@@ -3,13 +3,17 @@
 // Use function `openapi.FromImplementation` to convert
 // an existing API to an OpenAPI specification. You may
 // cache this specification and expose it as an entrypoint
-// to generate a user-friendly documentation.
+// to generate a user-friendly documentation, e.g. with
+// `docs.NewHandler`.
 package openapi
 
 import (
+	"errors"
 	"fmt"
-	"log/slog"
+	"slices"
+	"strings"
 
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
 	"github.com/pasqal-io/gousset/openapi/header"
@@ -24,6 +28,10 @@ import (
 // The version of OpenAPI specs we're based on.
 const OpenApiVersion = "3.0.1"
 
+// The version of OpenAPI specs required by features that did
+// not exist in OpenApiVersion, e.g. webhooks.
+const OpenApiVersion31 = "3.1.0"
+
 // Contact information for the exposed API.
 type Contact struct {
 	// The identifying name of the contact person/organization.
@@ -80,6 +88,11 @@ type Spec struct {
 	// All the routes covered by this API.
 	Paths map[path.Route]path.Spec `json:"paths"`
 
+	// The incoming webhooks that MAY be received as part of this API and that the API consumer MAY choose to implement. The key name is a unique string to refer to each webhook.
+	//
+	// Requires OpenAPI 3.1.
+	Webhooks map[string]path.Spec `json:"webhooks,omitempty"`
+
 	// A set of reusable objects for different aspects of the OAS. All objects defined within the Components Object will have no effect on the API unless they are explicitly referenced from outside the Components Object
 	Components Components `json:"components"`
 
@@ -128,12 +141,99 @@ type Implementation struct {
 
 	// Definition of the security schemes used for endpoints.
 	SecuritySchemes *map[string]security.Scheme `exhaustruct:"optional"`
+
+	// Requests that the service may send to its clients, independently
+	// from any operation, indexed by a unique name.
+	//
+	// The field `Path` of each Implementation is ignored and replaced
+	// with the key.
+	//
+	// Requires OpenApiVersion31.
+	Webhooks map[string]path.Implementation `exhaustruct:"optional"`
+
+	// The version of OpenAPI to target. If unspecified, OpenApiVersion.
+	OpenApiVersion string `exhaustruct:"optional"`
+
+	// If `true`, examples that do not conform to the schema they decorate
+	// cause an error. Otherwise, they are only reported as warnings.
+	//
+	// Equivalent to adding `diagnostic.CodeExampleMismatch` to `StrictCodes`.
+	Strict bool `exhaustruct:"optional"`
+
+	// Categories of diagnostics that cause an error rather than a warning,
+	// e.g. `diagnostic.CodeMissingDescription` to require descriptions.
+	StrictCodes []diagnostic.Code `exhaustruct:"optional"`
+
+	// If non-nil, receives the diagnostics found while building the spec,
+	// including those promoted to errors. Otherwise, they are logged.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// If provided, doc comments serve as descriptions of types, fields and
+	// operations, and the constants of named types serve as their enum,
+	// see package `extract`.
+	//
+	// Inherited by endpoints and webhooks unless they specify their own.
+	Comments doc.Comments `exhaustruct:"optional"`
+
+	// If `true`, named types that contain `readOnly` or `writeOnly` fields
+	// and are used both as request and response bodies are documented as two
+	// component schemas, e.g. `UserInput` without the `readOnly` fields and
+	// `UserOutput` without the `writeOnly` fields.
+	SplitReadWriteSchemas bool `exhaustruct:"optional"`
+
+	// How the `Path` of endpoints is converted into a route, e.g.
+	// `/users/:userId` into `/users/{userId}`.
+	Routes path.RouteOptions `exhaustruct:"optional"`
+
+	// If `true`, when some endpoints cannot be documented, `FromImplementation`
+	// returns the spec of the others along with the error, e.g. to preview the
+	// documentation while fixing a large API.
+	Partial bool `exhaustruct:"optional"`
 }
 
 // Build a complete OpenAPI spec from a description of an implementation.
+//
+// Errors are collected across endpoints, operations, parameters and schemas
+// and returned together, use `errors.As` with `diagnostic.Error` to find their
+// location. See `implem.Partial` to obtain the spec of the remaining endpoints.
+//
+// Problems that do not prevent building the spec, e.g. missing descriptions,
+// are reported to `implem.Diagnostics` or promoted to errors, see `implem.StrictCodes`.
 func FromImplementation(implem Implementation) (Spec, error) {
+	report := diagnostic.NewReport()
+	result, err := fromImplementation(implem, report)
+	err = diagnostic.Join(err, publishDiagnostics(implem, report))
+	if err != nil && !implem.Partial {
+		return Spec{}, err
+	}
+	return result, err
+}
+
+// Forward the diagnostics found while building a spec, promoting those
+// listed in `implem.StrictCodes` to errors.
+func publishDiagnostics(implem Implementation, report *diagnostic.Report) error {
+	var promoted []error
+	for _, d := range report.Diagnostics() {
+		if slices.Contains(implem.StrictCodes, d.Code) {
+			d.Severity = diagnostic.SeverityError
+			promoted = append(promoted, d)
+		}
+		// If `implem.Diagnostics` is nil, this logs the diagnostic.
+		implem.Diagnostics.Add(d)
+	}
+	if len(promoted) != 0 {
+		return fmt.Errorf("%d diagnostics promoted to errors: %w", len(promoted), errors.Join(promoted...))
+	}
+	return nil
+}
+
+func fromImplementation(implem Implementation, report *diagnostic.Report) (Spec, error) {
+	version := implem.OpenApiVersion
+	if version == "" {
+		version = OpenApiVersion
+	}
 	result := Spec{
-		OpenApiVersion: OpenApiVersion,
+		OpenApiVersion: version,
 		Info:           implem.Info,
 		ExternalDocs:   implem.ExternalDocs,
 		Components: Components{
@@ -141,24 +241,104 @@ func FromImplementation(implem Implementation) (Spec, error) {
 		},
 	}
 	if result.Info.Title == "" {
-		slog.Warn("gousset.openapi.FromImplementation: missing title")
+		report.Add(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMissingTitle,
+			Message:  "missing title",
+			Location: "/info/title",
+		})
 	}
 	if result.Info.Description == nil {
-		slog.Warn("gousset.openapi.FromImplementation: missing description")
+		report.Add(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMissingDescription,
+			Message:  "missing description",
+			Location: "/info/description",
+		})
 	}
 
+	// Errors are collected across endpoints, to report them all at once.
+	var errs []error
 	paths := make(map[path.Route]path.Spec)
 	result.Paths = paths
+	// The path defining each route, to detect collisions, e.g. `/users/:id` and `/users/{id}`.
+	definedBy := make(map[path.Route]string)
 	for _, pathImpl := range implem.Endpoints {
-		route, err := path.MakeRoute(pathImpl.Path)
+		at := diagnostic.Error{Path: pathImpl.Path}
+		route, captures, err := path.ParseRoute(pathImpl.Path, implem.Routes)
 		if err != nil {
-			return Spec{}, fmt.Errorf("invalid path: %w", err)
+			errs = append(errs, diagnostic.Locate(fmt.Errorf("invalid path: %w", err), at))
+			continue
+		}
+		if previous, ok := definedBy[route]; ok {
+			errs = append(errs,
+				diagnostic.Error{Path: previous, Err: fmt.Errorf("route %s is also defined by path %s", route, pathImpl.Path)},
+				diagnostic.Error{Path: pathImpl.Path, Err: fmt.Errorf("route %s is already defined by path %s", route, previous)},
+			)
+			continue
+		}
+		definedBy[route] = pathImpl.Path
+		if pathImpl.Diagnostics == nil {
+			pathImpl.Diagnostics = report
+		}
+		if pathImpl.Comments == nil {
+			pathImpl.Comments = implem.Comments
 		}
 		pathSpec, err := path.FromPath(pathImpl)
 		if err != nil {
-			return Spec{}, fmt.Errorf("failed to build spec for route %s: %w", route, err)
+			errs = append(errs, diagnostic.Locate(err, at))
+			if len(pathSpec.Operations()) == 0 {
+				continue
+			}
+		}
+		if err := path.CheckParameters(route, pathSpec); err != nil {
+			errs = append(errs, diagnostic.Locate(diagnostic.Wrap(err, "invalid path parameters"), at))
+		}
+		if err := path.ApplyCaptures(pathSpec, captures); err != nil {
+			errs = append(errs, diagnostic.Locate(fmt.Errorf("invalid path parameters: %w", err), at))
 		}
 		paths[route] = pathSpec
 	}
+	if len(implem.Webhooks) != 0 && !strings.HasPrefix(version, "3.1") {
+		errs = append(errs, diagnostic.Error{Err: fmt.Errorf("webhooks require OpenAPI %s, but targeting OpenAPI %s", OpenApiVersion31, version)})
+	} else if len(implem.Webhooks) != 0 {
+		result.Webhooks = make(map[string]path.Spec)
+		for _, name := range sortedKeys(implem.Webhooks) {
+			pathImpl := implem.Webhooks[name]
+			pathImpl.Path = name
+			if pathImpl.Diagnostics == nil {
+				pathImpl.Diagnostics = report
+			}
+			if pathImpl.Comments == nil {
+				pathImpl.Comments = implem.Comments
+			}
+			pathSpec, err := path.FromPath(pathImpl)
+			if err != nil {
+				errs = append(errs, diagnostic.Locate(diagnostic.Wrap(err, fmt.Sprint("in webhook ", name)), diagnostic.Error{Path: name}))
+				if len(pathSpec.Operations()) == 0 {
+					continue
+				}
+			}
+			result.Webhooks[name] = pathSpec
+		}
+	}
+	if implem.SplitReadWriteSchemas {
+		if err := splitReadWrite(implem, &result); err != nil {
+			errs = append(errs, fmt.Errorf("while splitting read/write schemas: %w", err))
+		}
+	}
+	if err := collectParameterComponents(&result); err != nil {
+		errs = append(errs, fmt.Errorf("while collecting component parameters: %w", err))
+	}
+	if len(errs) != 0 {
+		// Links and examples may refer to the operations that failed, checking them would only add noise.
+		return result, diagnostic.Join(errs...)
+	}
+	if err := validateLinks(result); err != nil {
+		return result, err
+	}
+	if err := checkExamples(result, implem.Strict || slices.Contains(implem.StrictCodes, diagnostic.CodeExampleMismatch), report); err != nil {
+		return result, err
+	}
 	return result, nil
 }
@@ -1,15 +1,26 @@
 package openapi_test
 
 import (
+	"errors"
+	"maps"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
+	"github.com/pasqal-io/gousset/openapi/example"
+	"github.com/pasqal-io/gousset/openapi/expression"
+	"github.com/pasqal-io/gousset/openapi/link"
+	"github.com/pasqal-io/gousset/openapi/media"
+	"github.com/pasqal-io/gousset/openapi/operation"
 	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/response"
 	"github.com/pasqal-io/gousset/shared"
 	"github.com/pasqal-io/gousset/shared/structs"
 	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
 )
 
 // Test on an empty spec.
@@ -181,39 +192,39 @@ func TestEndpoints(t *testing.T) {
 			"/v1/{foo}/{bar}": {
 			"summary": "A very interesting endpoint",
 			"description": "With additional description",
+			"parameters": [
+			{
+				"description": "I am foo",
+				"in": "path",
+				"name": "foo",
+				"required": true,
+				"schema": {
+				"type": "string"
+				}
+			},
+			{
+				"description": "I am bar",
+				"in": "path",
+				"name": "bar",
+				"required": true,
+				"schema": {
+				"type": "string"
+				}
+			},
+			{
+				"description": "I am sna",
+				"in": "query",
+				"name": "sna",
+				"required": true,
+				"schema": {
+				"format": "int32",
+				"type": "number"
+				}
+			}
+			],
 			"get": {
 				"summary": "This is the summary for GET /foo/bar",
 				"operationId": "get /v1/{foo}/{bar}",
-				"parameters": [
-				{
-					"description": "I am foo",
-					"in": "path",
-					"name": "foo",
-					"required": true,
-					"schema": {
-					"type": "string"
-					}
-				},
-				{
-					"description": "I am bar",
-					"in": "path",
-					"name": "bar",
-					"required": true,
-					"schema": {
-					"type": "string"
-					}
-				},
-				{
-					"description": "I am sna",
-					"in": "query",
-					"name": "sna",
-					"required": true,
-					"schema": {
-					"format": "int32",
-					"type": "number"
-					}
-				}
-				],
 				"responses": {
 				"default": {
 					"description": ""
@@ -223,36 +234,6 @@ func TestEndpoints(t *testing.T) {
 			"put": {
 				"summary": "This is the summary for PUT /foo/bar",
 				"operationId": "put /v1/{foo}/{bar}",
-				"parameters": [
-				{
-					"description": "I am foo",
-					"in": "path",
-					"name": "foo",
-					"required": true,
-					"schema": {
-					"type": "string"
-					}
-				},
-				{
-					"description": "I am bar",
-					"in": "path",
-					"name": "bar",
-					"required": true,
-					"schema": {
-					"type": "string"
-					}
-				},
-				{
-					"description": "I am sna",
-					"in": "query",
-					"name": "sna",
-					"required": true,
-					"schema": {
-					"format": "int32",
-					"type": "number"
-					}
-				}
-				],
 				"requestBody": {
 				"required": true,
 				"content": {
@@ -266,6 +247,7 @@ func TestEndpoints(t *testing.T) {
 						],
 						"properties": {
 						"bu": {
+							"description": "I am bu",
 							"type": "array",
 							"items": {
 							"type": "number",
@@ -273,9 +255,11 @@ func TestEndpoints(t *testing.T) {
 							}
 						},
 						"ga": {
+							"description": "I am ga",
 							"type": "boolean"
 						},
 						"zo": {
+							"description": "I am zo",
 							"type": "number",
 							"format": "double"
 						}
@@ -295,3 +279,796 @@ func TestEndpoints(t *testing.T) {
 		"components": {}
 		}`, openapi.OpenApiVersion)
 }
+
+// Test links between operations.
+
+type OrderPath struct {
+	Id string `path:"id" description:"The id of the order"`
+}
+
+type OrderBody struct {
+	Item string `json:"item" description:"The item to order"`
+}
+
+type CreatedOrder struct {
+	Id string `json:"id"`
+}
+
+func linkedImplementation(links map[string]link.Implementation) openapi.Implementation {
+	return openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/orders",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Post: {
+						Input: reflect.TypeFor[structs.Body[OrderBody]](),
+						Response: response.Implementation{
+							Default: response.ResponseImplementation{
+								Description: "The order was created",
+								Content: &map[string]media.Implementation{
+									"application/json": {
+										Type: reflect.TypeFor[CreatedOrder](),
+									},
+								},
+								Links: &links,
+							},
+						},
+					},
+				},
+			},
+			{
+				Path: "/orders/:id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[OrderPath]](),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLinks(t *testing.T) {
+	spec, err := openapi.FromImplementation(linkedImplementation(map[string]link.Implementation{
+		"GetOrder": {
+			OperationId: shared.Ptr(operation.MakeId("get", "/orders/:id")),
+			Parameters: &map[string]shared.Json{
+				"path.id": expression.ResponseBody("id"),
+			},
+			Description: shared.Ptr("Fetch the order that was just created"),
+		},
+	}))
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Paths["/orders"].Post.Responses.Default, `{
+		"description": "The order was created",
+		"content": {
+			"application/json": {
+				"schema": {
+					"type": "object",
+					"required": ["id"],
+					"properties": {
+						"id": {"type": "string"}
+					}
+				}
+			}
+		},
+		"links": {
+			"GetOrder": {
+				"operationId": "get /orders/:id",
+				"parameters": {
+					"path.id": "$response.body#/id"
+				},
+				"description": "Fetch the order that was just created"
+			}
+		}
+	}`)
+}
+
+// Test that a typo in a path parameter is detected.
+func TestPathParameterMismatch(t *testing.T) {
+	_, err := openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/orders/:order_id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[OrderPath]](),
+					},
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "in operation get /orders/:order_id, template variable {order_id} of route /orders/{order_id} is not declared as a path parameter")
+}
+
+type ItemPath struct {
+	ItemId int `path:"itemId" description:"The id of the item"`
+}
+
+// Test that captures keep their name and document their regex.
+func TestRouteCaptures(t *testing.T) {
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/items/{itemId:[0-9]+}",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[ItemPath]](),
+					},
+				},
+			},
+			{
+				Path: "/items/:itemId/reviews",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[ItemPath]](),
+					},
+				},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Paths["/items/{itemId}"].Get.Parameters, `[
+		{
+			"name": "itemId",
+			"in": "path",
+			"description": "The id of the item",
+			"required": true,
+			"schema": {
+				"type": "number",
+				"format": "int32",
+				"pattern": "^[0-9]+$"
+			}
+		}
+	]`)
+	testutils.EqualJSON(t, spec.Paths["/items/{itemId}/reviews"].Get.Parameters, `[
+		{
+			"name": "itemId",
+			"in": "path",
+			"description": "The id of the item",
+			"required": true,
+			"schema": {
+				"type": "number",
+				"format": "int32"
+			}
+		}
+	]`)
+
+	// With a conversion, names must match the converted names.
+	_, err = openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/items/:itemId",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[ItemPath]](),
+					},
+				},
+			},
+		},
+		Routes: path.RouteOptions{Case: path.CaseSnake},
+	})
+	assert.ErrorContains(t, err, "template variable {item_id} of route /items/{item_id} is not declared as a path parameter")
+}
+
+func TestLinksToUnknownOperation(t *testing.T) {
+	_, err := openapi.FromImplementation(linkedImplementation(map[string]link.Implementation{
+		"GetOrder": {
+			OperationId: shared.Ptr("get /nowhere"),
+		},
+	}))
+	assert.ErrorContains(t, err, "unknown operationId \"get /nowhere\"")
+}
+
+func TestLinksToUnknownParameter(t *testing.T) {
+	_, err := openapi.FromImplementation(linkedImplementation(map[string]link.Implementation{
+		"GetOrder": {
+			OperationRef: shared.Ptr("#/paths/~1orders~1{id}/get"),
+			Parameters: &map[string]shared.Json{
+				"order_id": expression.ResponseBody("id"),
+			},
+		},
+	}))
+	assert.ErrorContains(t, err, "parameter order_id is not a parameter of target operation")
+}
+
+func TestLinksFromUnknownRequestParameter(t *testing.T) {
+	_, err := openapi.FromImplementation(linkedImplementation(map[string]link.Implementation{
+		"GetOrder": {
+			OperationRef: shared.Ptr("#/paths/~1orders~1{id}/get"),
+			Parameters: &map[string]shared.Json{
+				"id": expression.RequestPath("id"),
+			},
+		},
+	}))
+	assert.ErrorContains(t, err, "refers to an unknown path parameter")
+}
+
+// Percent-encoded references resolve, and plain strings starting with `$` are checked as expressions.
+func TestLinksEncoded(t *testing.T) {
+	_, err := openapi.FromImplementation(linkedImplementation(map[string]link.Implementation{
+		"GetOrder": {
+			OperationRef: shared.Ptr("#/paths/~1orders~1%7Bid%7D/get"),
+			Parameters: &map[string]shared.Json{
+				"id": "$response.body#/id",
+			},
+		},
+	}))
+	assert.NilError(t, err)
+
+	_, err = openapi.FromImplementation(linkedImplementation(map[string]link.Implementation{
+		"GetOrder": {
+			OperationRef: shared.Ptr("#/paths/~1orders~1%7Bid%7D/get"),
+			Parameters: &map[string]shared.Json{
+				"id": "$request.path.id",
+			},
+		},
+	}))
+	assert.ErrorContains(t, err, "refers to an unknown path parameter")
+}
+
+// With several invalid links, the error does not depend on map order.
+func TestLinksErrorsStable(t *testing.T) {
+	links := map[string]link.Implementation{}
+	for _, name := range []string{"A", "B", "C", "D"} {
+		links[name] = link.Implementation{OperationId: shared.Ptr("get /nowhere/" + name)}
+	}
+	for i := 0; i < 10; i++ {
+		_, err := openapi.FromImplementation(linkedImplementation(links))
+		assert.ErrorContains(t, err, "invalid link A ")
+	}
+}
+
+// Test webhooks.
+
+func TestWebhooks(t *testing.T) {
+	implem := openapi.Implementation{
+		Webhooks: map[string]path.Implementation{
+			"jobCompleted": {
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Post: {
+						Input:   reflect.TypeFor[structs.Body[CreatedOrder]](),
+						Summary: "A job is complete",
+					},
+				},
+			},
+		},
+	}
+	_, err := openapi.FromImplementation(implem)
+	assert.ErrorContains(t, err, "webhooks require OpenAPI 3.1.0")
+
+	implem.OpenApiVersion = openapi.OpenApiVersion31
+	spec, err := openapi.FromImplementation(implem)
+	assert.NilError(t, err)
+	testutils.EqualJSONf(t, spec, `{
+		"openapi": "%s",
+		"info": {
+			"title": "",
+			"version": ""
+		},
+		"paths": {},
+		"webhooks": {
+			"jobCompleted": {
+				"post": {
+					"summary": "A job is complete",
+					"operationId": "post jobCompleted",
+					"requestBody": {
+						"required": true,
+						"content": {
+							"application/json": {
+								"schema": {
+									"type": "object",
+									"required": ["id"],
+									"properties": {
+										"id": {"type": "string"}
+									}
+								}
+							}
+						}
+					},
+					"responses": {
+						"default": {
+							"description": ""
+						}
+					}
+				}
+			}
+		},
+		"components": {}
+	}`, openapi.OpenApiVersion31)
+}
+
+// Test that examples are checked against their schemas.
+
+type Temperature float64
+
+func (Temperature) Example() shared.Json {
+	return "hot"
+}
+
+type Weather struct {
+	Temperature Temperature `json:"temperature"`
+	City        string      `json:"city" minLength:"1"`
+}
+
+func weatherImplementation(strict bool) openapi.Implementation {
+	return openapi.Implementation{
+		Strict: strict,
+		Endpoints: []path.Implementation{
+			{
+				Path: "/weather",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Response: response.Implementation{
+							Default: response.ResponseImplementation{
+								Description: "The weather",
+								Content: &map[string]media.Implementation{
+									"application/json": {
+										Type: reflect.TypeFor[Weather](),
+										Example: shared.Ptr[shared.Json](Weather{
+											Temperature: 20,
+											City:        "",
+										}),
+										Examples: &map[string]example.Example{
+											"paris": example.Spec{
+												Summary: "In Paris",
+												Value: shared.Ptr[shared.Json](Weather{
+													Temperature: 20,
+													City:        "Paris",
+												}),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestExamplesStrict(t *testing.T) {
+	_, err := openapi.FromImplementation(weatherImplementation(true))
+	assert.Error(t, err, "invalid examples: "+
+		"example at /paths/~1weather/get/responses/default/content/application~1json/schema/properties/temperature/example does not conform to its schema: expected number, got string\n"+
+		"example at /paths/~1weather/get/responses/default/content/application~1json/example does not conform to its schema: /city: expected at least 1 characters, got 0")
+	var mismatch openapi.ExampleMismatch
+	assert.Assert(t, errors.As(err, &mismatch))
+	assert.Equal(t, mismatch.Location, "/paths/~1weather/get/responses/default/content/application~1json/schema/properties/temperature/example")
+
+	// Outside of strict mode, mismatches are only warnings.
+	spec, err := openapi.FromImplementation(weatherImplementation(false))
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Paths["/weather"].Get.Responses.Default, `{
+		"description": "The weather",
+		"content": {
+			"application/json": {
+				"schema": "<<PRESENCE>>",
+				"example": {"temperature": 20, "city": ""},
+				"examples": {
+					"paris": {
+						"summary": "In Paris",
+						"value": {"temperature": 20, "city": "Paris"}
+					}
+				}
+			}
+		}
+	}`)
+}
+
+// Test that types with readOnly/writeOnly fields used in both directions may be split.
+
+type Account struct {
+	Id       string `json:"id" readOnly:""`
+	Name     string `json:"name"`
+	Password string `json:"password" writeOnly:""`
+}
+
+func accountImplementation(split bool) openapi.Implementation {
+	return openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/accounts",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Post: {
+						Input: reflect.TypeFor[structs.Body[Account]](),
+						Response: response.Implementation{
+							Default: response.ResponseImplementation{
+								Description: "The account was created",
+								Content: &map[string]media.Implementation{
+									"application/json": {
+										Type: reflect.TypeFor[*Account](),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		SplitReadWriteSchemas: split,
+	}
+}
+
+func TestSplitReadWriteSchemas(t *testing.T) {
+	spec, err := openapi.FromImplementation(accountImplementation(false))
+	assert.NilError(t, err)
+	assert.Assert(t, spec.Components.Schemas == nil)
+
+	spec, err = openapi.FromImplementation(accountImplementation(true))
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Components.Schemas, `{
+		"AccountInput": {
+			"type": "object",
+			"required": ["name", "password"],
+			"properties": {
+				"name": {"type": "string"},
+				"password": {"type": "string", "writeOnly": true}
+			}
+		},
+		"AccountOutput": {
+			"type": "object",
+			"required": ["id", "name"],
+			"properties": {
+				"id": {"type": "string", "readOnly": true},
+				"name": {"type": "string"}
+			}
+		}
+	}`)
+	testutils.EqualJSON(t, spec.Paths["/accounts"].Post.Request, `{
+		"required": true,
+		"content": {
+			"application/json": {
+				"schema": {"$ref": "#/components/schemas/AccountInput"}
+			}
+		}
+	}`)
+	testutils.EqualJSON(t, spec.Paths["/accounts"].Post.Responses.Default, `{
+		"description": "The account was created",
+		"content": {
+			"application/json": {
+				"schema": {"$ref": "#/components/schemas/AccountOutput"}
+			}
+		}
+	}`)
+}
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+}
+
+// Named so that its component collides with that of `Page[Account]`.
+type Page_Account struct {
+	Items []Account `json:"items"`
+}
+
+func pageImplementation(body reflect.Type, output reflect.Type) openapi.Implementation {
+	return openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/accounts",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Put: {
+						Input: reflect.StructOf([]reflect.StructField{{Name: "Body", Type: body}}),
+						Response: response.Implementation{
+							Default: response.ResponseImplementation{
+								Description: "The accounts were replaced",
+								Content: &map[string]media.Implementation{
+									"application/json": {Type: output},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		SplitReadWriteSchemas: true,
+	}
+}
+
+// Test that generic types are split into valid components, and that collisions are detected.
+func TestSplitReadWriteSchemasNames(t *testing.T) {
+	spec, err := openapi.FromImplementation(pageImplementation(reflect.TypeFor[Page[Account]](), reflect.TypeFor[Page[Account]]()))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, slices.Sorted(maps.Keys(*spec.Components.Schemas)), []string{"Page_AccountInput", "Page_AccountOutput"})
+
+	implem := pageImplementation(reflect.TypeFor[Page[Account]](), reflect.TypeFor[Page[Account]]())
+	implem.Endpoints = append(implem.Endpoints, pageImplementation(reflect.TypeFor[Page_Account](), reflect.TypeFor[Page_Account]()).Endpoints[0])
+	implem.Endpoints[1].Path = "/other-accounts"
+	_, err = openapi.FromImplementation(implem)
+	assert.ErrorContains(t, err, "as they both map to component Page_Account")
+}
+
+// Test parameters documented as components.
+
+type RequestId string
+
+func (RequestId) ParameterComponent() string {
+	return "RequestId"
+}
+
+type TracedHeader struct {
+	RequestId RequestId `header:"X-Request-Id" description:"A unique id for this request"`
+}
+
+type OtherTracedHeader struct {
+	RequestId RequestId `header:"X-Correlation-Id" description:"A unique id for this request"`
+}
+
+func TestParameterComponents(t *testing.T) {
+	endpoint := func(route string, input reflect.Type) path.Implementation {
+		return path.Implementation{
+			Path: route,
+			PerVerb: map[path.Verb]path.VerbImplementation{
+				path.Get: {Input: input},
+			},
+		}
+	}
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			endpoint("/orders", reflect.TypeFor[struct{ Header TracedHeader }]()),
+			endpoint("/orders/:id", reflect.TypeFor[struct {
+				Path   OrderPath
+				Header TracedHeader
+			}]()),
+		},
+	})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Components, `{
+		"parameters": {
+			"RequestId": {
+				"name": "X-Request-Id",
+				"in": "header",
+				"description": "A unique id for this request",
+				"required": true,
+				"schema": {"type": "string"}
+			}
+		}
+	}`)
+	testutils.EqualJSON(t, spec.Paths["/orders/{id}"].Get.Parameters, `[
+		{
+			"name": "id",
+			"in": "path",
+			"description": "The id of the order",
+			"required": true,
+			"schema": {"type": "string"}
+		},
+		{"$ref": "#/components/parameters/RequestId"}
+	]`)
+
+	// The same component may not be defined twice differently.
+	_, err = openapi.FromImplementation(openapi.Implementation{
+		Endpoints: []path.Implementation{
+			endpoint("/orders", reflect.TypeFor[struct{ Header TracedHeader }]()),
+			endpoint("/invoices", reflect.TypeFor[struct{ Header OtherTracedHeader }]()),
+		},
+	})
+	assert.ErrorContains(t, err, "conflicting definitions of component parameter RequestId at /paths/~1invoices/get/parameters/0 and /paths/~1orders/get/parameters/0")
+}
+
+// Test that problems are reported as diagnostics and may be promoted to errors.
+
+type AccountPath struct {
+	Id string `path:"id"`
+}
+
+type AccountHeader struct {
+	Authorization string `header:"Authorization" description:"The credentials"`
+}
+
+func accountDiagnosticsImplementation() openapi.Implementation {
+	return openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/accounts/{id}",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[struct {
+							Path   AccountPath
+							Header AccountHeader
+						}](),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	implem := accountDiagnosticsImplementation()
+	implem.Diagnostics = diagnostic.NewReport()
+	_, err := openapi.FromImplementation(implem)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, implem.Diagnostics.Diagnostics(), []diagnostic.Diagnostic{
+		{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMissingTitle,
+			Message:  "missing title",
+			Location: "/info/title",
+		},
+		{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMissingDescription,
+			Message:  "missing description",
+			Location: "/info/description",
+		},
+		{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMissingDescription,
+			Message:  "path parameter id is missing a description, please add a tag `description` or a doc comment to the field or a method `Description()` to its type",
+			Endpoint: "get /accounts/{id}",
+			Type:     "openapi_test.AccountPath",
+			Field:    "Id",
+		},
+		{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeReservedHeader,
+			Message:  "header Authorization is ignored by OpenAPI, skipping it, document it with a security scheme instead, e.g. `security.Spec{Type: security.TypeHttp, Http: &security.Http{Scheme: \"bearer\"}}` in `openapi.Implementation.SecuritySchemes`",
+			Endpoint: "get /accounts/{id}",
+			Type:     "openapi_test.AccountHeader",
+			Field:    "Authorization",
+		},
+	})
+
+	// Example mismatches are diagnostics, too.
+	weather := weatherImplementation(false)
+	weather.Diagnostics = diagnostic.NewReport()
+	_, err = openapi.FromImplementation(weather)
+	assert.NilError(t, err)
+	var locations []string
+	for _, d := range weather.Diagnostics.Diagnostics() {
+		if d.Code == diagnostic.CodeExampleMismatch {
+			locations = append(locations, d.Location)
+		}
+	}
+	assert.DeepEqual(t, locations, []string{
+		"/paths/~1weather/get/responses/default/content/application~1json/schema/properties/temperature/example",
+		"/paths/~1weather/get/responses/default/content/application~1json/example",
+	})
+}
+
+func TestDiagnosticsStrict(t *testing.T) {
+	implem := accountDiagnosticsImplementation()
+	implem.Info = openapi.Info{Title: "Accounts", Description: shared.Ptr("Manage accounts"), Version: "1.0"}
+	implem.StrictCodes = []diagnostic.Code{diagnostic.CodeMissingDescription}
+	implem.Diagnostics = diagnostic.NewReport()
+	_, err := openapi.FromImplementation(implem)
+	assert.Error(t, err, "1 diagnostics promoted to errors: "+
+		"error [missing-description] in get /accounts/{id} in openapi_test.AccountPath.Id: path parameter id is missing a description, please add a tag `description` or a doc comment to the field or a method `Description()` to its type")
+	var promoted diagnostic.Diagnostic
+	assert.Assert(t, errors.As(err, &promoted))
+	assert.Equal(t, promoted.Field, "Id")
+
+	// Other diagnostics remain warnings.
+	var severities []diagnostic.Severity
+	for _, d := range implem.Diagnostics.Diagnostics() {
+		severities = append(severities, d.Severity)
+	}
+	assert.DeepEqual(t, severities, []diagnostic.Severity{diagnostic.SeverityError, diagnostic.SeverityWarning})
+
+	// Promoting example mismatches is equivalent to `Strict`.
+	weather := weatherImplementation(false)
+	weather.StrictCodes = []diagnostic.Code{diagnostic.CodeExampleMismatch}
+	_, err = openapi.FromImplementation(weather)
+	var mismatch openapi.ExampleMismatch
+	assert.Assert(t, errors.As(err, &mismatch))
+}
+
+// Test that errors are collected across endpoints.
+
+type BrokenQuery struct {
+	Ids  []int  `query:"ids" description:"The ids" style:"csv"`
+	Page string `query:"page" description:"The page" style:"label"`
+}
+
+type BrokenAddress struct {
+	City string
+}
+
+type BrokenBody struct {
+	Name    string        `json:"name"`
+	Address BrokenAddress `json:"address"`
+}
+
+func brokenImplementation() openapi.Implementation {
+	return openapi.Implementation{
+		Info: openapi.Info{Title: "Broken", Description: shared.Ptr("Broken"), Version: "1.0"},
+		Endpoints: []path.Implementation{
+			{
+				Path: "/users",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Query[BrokenQuery]](),
+					},
+					path.Post: {
+						Input: reflect.TypeFor[structs.Body[BrokenBody]](),
+					},
+					path.Delete: {},
+				},
+			},
+			{
+				Path: "/health",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {},
+				},
+			},
+		},
+	}
+}
+
+func TestErrorAggregation(t *testing.T) {
+	_, err := openapi.FromImplementation(brokenImplementation())
+	joined, ok := err.(interface{ Unwrap() []error })
+	assert.Assert(t, ok, err)
+	type location struct{ Path, Verb, Type, Field string }
+	var locations []location
+	for _, err := range joined.Unwrap() {
+		var located diagnostic.Error
+		assert.Assert(t, errors.As(err, &located), err)
+		locations = append(locations, location{located.Path, located.Verb, located.Type, located.Field})
+	}
+	assert.DeepEqual(t, locations, []location{
+		{"/users", "get", "openapi_test.BrokenQuery", "Ids"},
+		{"/users", "get", "openapi_test.BrokenQuery", "Page"},
+		{"/users", "post", "openapi_test.BrokenAddress", "City"},
+	})
+	assert.Error(t, err, "in operation get /users, in field openapi_test.BrokenQuery.Ids: while compiling individual parameter from field openapi_test.BrokenQuery.Ids, invalid serialization: unknown style \"csv\"\n"+
+		"in operation get /users, in field openapi_test.BrokenQuery.Page: while compiling individual parameter from field openapi_test.BrokenQuery.Page, invalid serialization: style label may not be used for query parameters\n"+
+		"in operation post /users, in field openapi_test.BrokenAddress.City: failed to extract the type of body openapi_test.BrokenBody: while compiling schema for struct openapi_test.BrokenAddress, field City doesn't have a public name, expecting a tag `json`")
+
+	// On demand, the endpoints that could be documented are returned nonetheless.
+	implem := brokenImplementation()
+	implem.Partial = true
+	spec, err := openapi.FromImplementation(implem)
+	assert.Assert(t, err != nil)
+	assert.Assert(t, spec.Paths["/health"].Get != nil)
+	users := spec.Paths["/users"]
+	assert.Assert(t, users.Delete != nil)
+	assert.Assert(t, users.Get == nil)
+	assert.Assert(t, users.Post == nil)
+}
+
+func TestRouteCollision(t *testing.T) {
+	implem := openapi.Implementation{
+		Endpoints: []path.Implementation{
+			{
+				Path: "/orders/:id",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Get: {
+						Input: reflect.TypeFor[structs.Path[OrderPath]](),
+					},
+				},
+			},
+			{
+				Path: "/orders/{id}",
+				PerVerb: map[path.Verb]path.VerbImplementation{
+					path.Delete: {
+						Input: reflect.TypeFor[structs.Path[OrderPath]](),
+					},
+				},
+			},
+		},
+	}
+	_, err := openapi.FromImplementation(implem)
+	assert.Error(t, err, "at /orders/:id: route /orders/{id} is also defined by path /orders/{id}\n"+
+		"at /orders/{id}: route /orders/{id} is already defined by path /orders/:id")
+}
+
+func TestWebhooksPartial(t *testing.T) {
+	implem := brokenImplementation()
+	implem.Endpoints = implem.Endpoints[1:]
+	implem.Webhooks = map[string]path.Implementation{
+		"jobCompleted": {
+			PerVerb: map[path.Verb]path.VerbImplementation{
+				path.Post: {},
+			},
+		},
+	}
+	implem.Partial = true
+	spec, err := openapi.FromImplementation(implem)
+	assert.Error(t, err, "webhooks require OpenAPI 3.1.0, but targeting OpenAPI 3.0.1")
+	assert.Assert(t, spec.Paths["/health"].Get != nil)
+	assert.Assert(t, spec.Webhooks == nil)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/pasqal-io/gousset/openapi/callback"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/parameter"
 	"github.com/pasqal-io/gousset/openapi/request"
@@ -37,6 +39,9 @@ type Spec struct {
 	// The responses that this operation may return.
 	Responses response.Responses `json:"responses"`
 
+	// A map of possible out-of band callbacks related to the parent operation. The key is a unique identifier for the Callback Object.
+	Callbacks *map[string]callback.Callback `json:"callbacks,omitempty"`
+
 	// If true, this endpoint is deprecated.
 	Deprecated bool `json:"deprecated,omitempty"`
 }
@@ -62,66 +67,97 @@ type Implementation struct {
 	ExternalDocs *doc.External
 	Responses    response.Implementation
 	Deprecated   bool
+
+	// Where to report diagnostics. If nil, they are logged.
+	//
+	// Inherited by `Responses` unless it specifies its own.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`. If `Description`
+	// is nil, the doc comment of `Input` serves as description.
+	//
+	// Inherited by `Responses` unless it specifies its own.
+	Comments doc.Comments `exhaustruct:"optional"`
+}
+
+// The operationId assigned to the operation for a verb at a path, e.g. "get /v1/user/:id".
+//
+// Use this to refer to the operation, e.g. from a link.
+func MakeId(verb string, path string) string {
+	return fmt.Sprint(verb, " ", path)
 }
 
 // Extract an OpenAPI spec for an operation from a description of the implementation.
+//
+// Errors are collected across the input and the responses, each of them located
+// at this operation. On error, the parts that could be compiled are returned nonetheless.
 func FromImplementation(impl Implementation) (Spec, error) {
-	operationId := fmt.Sprint(impl.Verb, " ", impl.Path)
+	operationId := MakeId(impl.Verb, impl.Path)
 
+	description := impl.Description
+	if description == nil && impl.Input != nil && impl.Input.Kind() != reflect.Invalid {
+		description = doc.GetTypeComment(impl.Comments, impl.Input)
+	}
 	result := Spec{
 		Summary:              impl.Summary,
-		Description:          impl.Description,
+		Description:          description,
 		ExternalDocs:         impl.ExternalDocs,
 		OperationId:          operationId,
 		SecurityRequirements: impl.Security,
 		Deprecated:           impl.Deprecated,
 	}
 
-	addParameters := func(field string, in parameter.In, typ reflect.Type) error {
-		param, err := parameter.FromStruct(typ, in)
-		if err != nil {
-			return fmt.Errorf("while compiling operation %s %s, failed to extract spec for %s of %s: %w", impl.Verb, impl.Path, field, operationId, err)
-		}
-		result.Parameters = append(result.Parameters, param...)
-		return nil
-	}
 	// Zero value, assume the empty struct.
-	if impl.Input.Kind() == reflect.Invalid {
+	if impl.Input == nil || impl.Input.Kind() == reflect.Invalid {
 		// Note: `impl` is passed by copy, so this mutation is not observable.
 		impl.Input = reflect.TypeOf(structs.Nothing{})
 	}
+	var errs []error
 	for i := 0; i < impl.Input.NumField(); i++ {
 		field := impl.Input.Field(i)
+		at := diagnostic.Error{
+			Path:  impl.Path,
+			Verb:  impl.Verb,
+			Type:  impl.Input.String(),
+			Field: field.Name,
+		}
+		var in parameter.In
 		switch field.Name {
 		case "Body":
-			request, err := request.FromField(field)
+			request, err := request.FromImplementation(request.Implementation{Field: field, Diagnostics: impl.Diagnostics, Comments: impl.Comments})
 			if err != nil {
-				return Spec{}, fmt.Errorf("while compiling operation %s %s, failed to extract body spec for %s: %w", impl.Verb, impl.Path, operationId, err)
+				errs = append(errs, diagnostic.Locate(err, at))
+				continue
 			}
 			result.Request = &request
+			continue
 		case "Path":
-			err := addParameters(field.Name, parameter.InPath, field.Type)
-			if err != nil {
-				return Spec{}, err
-			}
+			in = parameter.InPath
 		case "Query":
-			err := addParameters(field.Name, parameter.InQuery, field.Type)
-			if err != nil {
-				return Spec{}, err
-			}
+			in = parameter.InQuery
 		case "Header":
-			err := addParameters(field.Name, parameter.InHeader, field.Type)
-			if err != nil {
-				return Spec{}, err
-			}
+			in = parameter.InHeader
 		default:
-			return Spec{}, fmt.Errorf("while compiling operation %s %s, invalid input type %s, it may not have fields other than Path, Query, Header, Body, found %s", impl.Verb, impl.Path, impl.Input.String(), field.Name)
+			at.Err = fmt.Errorf("while compiling operation %s %s, invalid input type %s, it may not have fields other than Path, Query, Header, Body, found %s", impl.Verb, impl.Path, impl.Input.String(), field.Name)
+			errs = append(errs, at)
+			continue
+		}
+		params, err := parameter.FromImplementation(parameter.Implementation{Type: field.Type, In: in, Diagnostics: impl.Diagnostics, Comments: impl.Comments})
+		if err != nil {
+			errs = append(errs, diagnostic.Locate(err, at))
 		}
+		result.Parameters = append(result.Parameters, params...)
+	}
+	if impl.Responses.Diagnostics == nil {
+		impl.Responses.Diagnostics = impl.Diagnostics
+	}
+	if impl.Responses.Comments == nil {
+		impl.Responses.Comments = impl.Comments
 	}
 	responses, err := response.FromImplementation(impl.Responses)
 	if err != nil {
-		return Spec{}, fmt.Errorf("while compiling operation %s %s, invalid response: %w", impl.Verb, impl.Path, err)
+		errs = append(errs, diagnostic.Locate(diagnostic.Wrap(err, "invalid response"), diagnostic.Error{Path: impl.Path, Verb: impl.Verb}))
 	}
 	result.Responses = responses
-	return result, nil
+	return result, diagnostic.Join(errs...)
 }
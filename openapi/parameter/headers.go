@@ -0,0 +1,9 @@
+package parameter
+
+// Headers that OpenAPI ignores when they are documented as parameters,
+// with a hint on how to document them instead.
+var reservedHeaders = map[string]string{
+	"Accept":        "document the media types of the responses instead",
+	"Content-Type":  "document the media types of the request body instead",
+	"Authorization": "document it with a security scheme instead, e.g. `security.Spec{Type: security.TypeHttp, Http: &security.Http{Scheme: \"bearer\"}}` in `openapi.Implementation.SecuritySchemes`",
+}
@@ -3,12 +3,16 @@ package parameter
 import (
 	"encoding/json"
 	"fmt"
-	"log/slog"
+	"mime"
+	"net/textproto"
 	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/iancoleman/strcase"
 	"github.com/pasqal-io/gousset/inner/serialization"
 	tags "github.com/pasqal-io/gousset/inner/tags"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
 	"github.com/pasqal-io/gousset/openapi/media"
@@ -39,6 +43,16 @@ type Spec struct {
 
 	Deprecated bool `json:"deprecated,omitempty"`
 
+	// If true, the parameter may be sent with an empty value, e.g. `?verbose=`.
+	// Query parameters only.
+	AllowEmptyValue bool `json:"allowEmptyValue,omitempty"`
+
+	// If true, the parameter matches the rest of the path, including slashes,
+	// e.g. `{name...}` in `net/http`. Path parameters only.
+	//
+	// OpenAPI cannot express this, so it is documented as an extension.
+	Rest bool `json:"x-rest,omitempty" exhaustruct:"optional"`
+
 	// Structure and syntax of the parameter.
 	//
 	// Mutually exclusive with Schema.
@@ -47,10 +61,16 @@ type Spec struct {
 	// Media type and schema for the parameter.
 	//
 	// Mutually exclusive with Content.
-	*SchemaSpec `json:"schema,omitempty"`
+	*SchemaSpec `json:"schema,omitempty" flatten:""`
 }
 
 func (s Spec) MarshalJSON() ([]byte, error) {
+	if s.ContentSpec != nil && s.SchemaSpec != nil {
+		return []byte{}, fmt.Errorf("invalid parameter %s, schema and content are mutually exclusive", s.Name)
+	}
+	if s.ContentSpec != nil && len(s.ContentSpec.Content) != 1 {
+		return []byte{}, fmt.Errorf("invalid parameter %s, content must contain exactly one media type, got %d", s.Name, len(s.ContentSpec.Content))
+	}
 	flattened, err := serialization.FlattenStructToJSON(s)
 	if err != nil {
 		return []byte{}, fmt.Errorf("error while flattening Spec for serialization: %w, ", err)
@@ -59,6 +79,10 @@ func (s Spec) MarshalJSON() ([]byte, error) {
 }
 
 func FromField(container reflect.Type, from reflect.StructField, in In) (Spec, error) {
+	return fromField(container, from, in, nil, nil)
+}
+
+func fromField(container reflect.Type, from reflect.StructField, in In, report *diagnostic.Report, comments doc.Comments) (Spec, error) {
 	publicNameKey := string(in)
 	tags, err := tags.Parse(from.Tag)
 	if err != nil {
@@ -68,79 +92,216 @@ func FromField(container reflect.Type, from reflect.StructField, in In) (Spec, e
 	// If a public name exists, use it.
 	publicFieldName := tags.PublicFieldName(publicNameKey)
 	if publicFieldName == nil {
-		if publicNameKey == "path" || publicNameKey == "query" {
+		switch publicNameKey {
+		case "path", "query":
 			publicFieldName = shared.Ptr(strcase.ToSnake(from.Name))
-		} else {
+		case "header":
+			publicFieldName = shared.Ptr(strcase.ToKebab(from.Name))
+		default:
 			publicFieldName = shared.Ptr(strcase.ToLowerCamel(from.Name))
 		}
-		slog.Warn("gousset.openapi.parameter.FromField: field is missing a tag with a public name, falling back to default",
-			"struct", container.String(),
-			"field", from.Name,
-			"origin", in,
-			"missing_tag", publicNameKey,
-			"default", *publicFieldName)
-	}
-
-	// Extract summary and description.
-	description := doc.GetDescription(from.Type)
-
-	if description == nil {
-		if tagSummary, ok := tags.Lookup("description"); ok && len(tagSummary) >= 1 {
-			description = shared.Ptr(tagSummary[0])
-		} else {
-			slog.Warn("gousset.openapi.parameter.FromField: field is missing a description, please add a tag `description` to the field or a method `Description()` to its type",
-				"struct", container.String(),
-				"field", from.Name)
+		report.Add(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMissingPublicName,
+			Message:  fmt.Sprintf("%s parameter is missing a tag `%s` with a public name, falling back to \"%s\"", in, publicNameKey, *publicFieldName),
+			Type:     container.String(),
+			Field:    from.Name,
+		})
+	}
+	if in == InHeader {
+		// Header names are case-insensitive.
+		publicFieldName = shared.Ptr(textproto.CanonicalMIMEHeaderKey(*publicFieldName))
+	}
+
+	// Extract description, giving priority to the field over its type.
+	var description *string
+	if tagSummary, ok := tags.Lookup("description"); ok && len(tagSummary) >= 1 {
+		description = shared.Ptr(tagSummary[0])
+	} else if description = doc.GetFieldDescription(comments, container, from); description == nil {
+		description = doc.GetDescriptionOrComment(comments, from.Type)
+		if description == nil {
+			report.Add(diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityWarning,
+				Code:     diagnostic.CodeMissingDescription,
+				Message:  fmt.Sprintf("%s parameter %s is missing a description, please add a tag `description` or a doc comment to the field or a method `Description()` to its type", in, *publicFieldName),
+				Type:     container.String(),
+				Field:    from.Name,
+			})
 		}
 	}
 
-	deprecated := false
-	if _, ok := tags.Lookup("deprecated"); ok {
-		deprecated = true
+	deprecated, err := tags.LookupFlag("deprecated")
+	if err != nil {
+		return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, invalid tag deprecated: %w", container.String(), from.Name, err)
 	}
 
+	// Path parameters are always required, as mandated by the spec.
 	required := true
-	if (tags.Default() != nil) || tags.IsPreinitialized() || (tags.MethodName() != nil) {
+	if in != InPath && ((tags.Default() != nil) || tags.IsPreinitialized() || (tags.MethodName() != nil)) {
 		required = false
 	}
+	if tags.MethodName() != nil {
+		// The default value is computed at runtime, so we cannot document it as a `default`.
+		description = doc.WithMethodNote(description)
+	}
+
+	// Parameters serialized with a media type, e.g. a JSON-encoded object, are documented with `content`.
+	if contentType := tags.LookupString("content"); contentType != nil {
+		if in != InQuery && in != InHeader {
+			return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, content may only be used for query and header parameters", container.String(), from.Name)
+		}
+		for _, key := range []string{"style", "explode", "allowReserved"} {
+			if _, ok := tags.Lookup(key); ok {
+				return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, %s may not be used with content", container.String(), from.Name, key)
+			}
+		}
+		contentSpec, err := contentFromField(container, from, *contentType, report, comments)
+		if err != nil {
+			return Spec{}, diagnostic.Locate(err, diagnostic.Error{Type: container.String(), Field: from.Name})
+		}
+		_, allowEmptyValue := tags.Lookup("allowEmptyValue")
+		if allowEmptyValue && in != InQuery {
+			return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, allowEmptyValue may only be used for query parameters", container.String(), from.Name)
+		}
+		return Spec{
+			Name:            *publicFieldName, // Non-nil, checked above.
+			In:              in,
+			Description:     description,
+			Deprecated:      deprecated,
+			AllowEmptyValue: allowEmptyValue,
+			Required:        required,
+			ContentSpec:     &contentSpec,
+		}, nil
+	}
 
-	schema, err := schema.FromImplementation(schema.Implementation{Type: from.Type, PublicNameKey: publicNameKey})
+	schemaImpl, err := schema.ImplementationFromFieldOf(container, from, publicNameKey, comments)
+	if err != nil {
+		return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s: %w", container.String(), from.Name, err)
+	}
+	schemaImpl.Diagnostics = report
+	// These are documented on the parameter rather than its schema.
+	schemaImpl.Description = nil
+	schemaImpl.Deprecated = false
+	schema, err := schema.FromImplementation(schemaImpl)
 	if err != nil {
-		return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, failed to find schema for field: %w", container.String(), from.Name, err)
+		// Errors within the type of the field are more precisely located than the field.
+		return Spec{}, diagnostic.Locate(err, diagnostic.Error{Type: container.String(), Field: from.Name})
 	}
 	schemaSpec := SchemaSpec{
 		Style:   nil,
 		Explode: nil,
 		Schema:  schema,
 	}
+	if style := tags.LookupString("style"); style != nil {
+		schemaSpec.Style = shared.Ptr(Style(*style))
+	}
+	schemaSpec.Explode, err = tags.LookupBool("explode")
+	if err != nil {
+		return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s: %w", container.String(), from.Name, err)
+	}
+	_, schemaSpec.AllowReserved = tags.Lookup("allowReserved")
+	if err := schemaSpec.validate(in); err != nil {
+		return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, invalid serialization: %w", container.String(), from.Name, err)
+	}
+	_, allowEmptyValue := tags.Lookup("allowEmptyValue")
+	if allowEmptyValue && in != InQuery {
+		return Spec{}, fmt.Errorf("while compiling individual parameter from field %s.%s, allowEmptyValue may only be used for query parameters", container.String(), from.Name)
+	}
 
 	return Spec{
-		Name:        *publicFieldName, // Non-nil, checked above.
-		In:          in,
-		Description: description,
-		Deprecated:  deprecated,
-		Required:    required,
-		SchemaSpec:  &schemaSpec,
+		Name:            *publicFieldName, // Non-nil, checked above.
+		In:              in,
+		Description:     description,
+		Deprecated:      deprecated,
+		AllowEmptyValue: allowEmptyValue,
+		Required:        required,
+		SchemaSpec:      &schemaSpec,
 	}, nil
 }
 
+// Compile the parameters declared by the fields of a struct.
+//
+// Errors are collected across fields. On error, the parameters that could
+// be compiled are returned nonetheless.
 func FromStruct(Struct reflect.Type, in In) ([]Parameter, error) {
+	return FromImplementation(Implementation{Type: Struct, In: in})
+}
+
+// User-provided metadata on a struct whose fields are parameters, e.g.
+// the type of field `Query` of an input.
+type Implementation struct {
+	Type reflect.Type
+	In   In
+
+	// Where to report diagnostics. If nil, they are logged.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	Comments doc.Comments `exhaustruct:"optional"`
+}
+
+// Compile the parameters declared by the fields of a struct.
+//
+// As `FromStruct`, with the options of `impl`.
+func FromImplementation(impl Implementation) ([]Parameter, error) {
+	Struct, in, report := impl.Type, impl.In, impl.Diagnostics
 	if Struct.Kind() != reflect.Struct {
 		return []Parameter{}, fmt.Errorf("while attempting to compile parameter list from struct, invalid type %s, expected a struct, got %v.", Struct.String(), Struct.Kind())
 	}
 
 	var parameters []Parameter
+	var errs []error
+	// The field defining each header, to detect duplicates.
+	headers := make(map[string]string)
 	for i := 0; i < Struct.NumField(); i++ { // We have checked above that it's a struct.
 		field := Struct.Field(i)
 		// FIXME: We'll need to know if there are any default values.
-		param, err := FromField(Struct, field, in) // FIXME: This fails if the field is flattened!
+		param, err := fromField(Struct, field, in, report, impl.Comments) // FIXME: This fails if the field is flattened!
 		if err != nil {
-			return []Parameter{}, fmt.Errorf("while attempting to compile parameter list from struct, failed to generate spec for parameter %s of type %s: %w", field.Name, Struct.String(), err)
+			errs = append(errs, diagnostic.Locate(err, diagnostic.Error{Type: Struct.String(), Field: field.Name}))
+			continue
+		}
+		if in == InHeader {
+			if hint, ok := reservedHeaders[param.Name]; ok {
+				report.Add(diagnostic.Diagnostic{
+					Severity: diagnostic.SeverityWarning,
+					Code:     diagnostic.CodeReservedHeader,
+					Message:  fmt.Sprintf("header %s is ignored by OpenAPI, skipping it, %s", param.Name, hint),
+					Type:     Struct.String(),
+					Field:    field.Name,
+				})
+				continue
+			}
+			if previous, ok := headers[param.Name]; ok {
+				errs = append(errs, diagnostic.Error{
+					Type:  Struct.String(),
+					Field: field.Name,
+					Err:   fmt.Errorf("while attempting to compile parameter list from struct, fields %s and %s of type %s both define header %s, header names are case-insensitive", previous, field.Name, Struct.String(), param.Name),
+				})
+				continue
+			}
+			headers[param.Name] = field.Name
+		}
+		if component, ok := reflect.New(field.Type).Interface().(IsComponent); ok {
+			name := component.ParameterComponent()
+			if !componentNameRegex.MatchString(name) {
+				errs = append(errs, diagnostic.Error{
+					Type:  Struct.String(),
+					Field: field.Name,
+					Err:   fmt.Errorf("while attempting to compile parameter list from struct, invalid component name \"%s\" for parameter %s of type %s", name, field.Name, Struct.String()),
+				})
+				continue
+			}
+			parameters = append(parameters, Reference{
+				Ref:        componentPrefix + name,
+				definition: &param,
+			})
+			continue
 		}
 		parameters = append(parameters, param)
 	}
 
-	return parameters, nil
+	return parameters, diagnostic.Join(errs...)
 }
 
 type In string
@@ -158,8 +319,20 @@ func (Spec) parameter() {
 
 var _ Parameter = Spec{}
 
-// Reference to a component.
+// Reference to a component, e.g. `#/components/parameters/RequestId`.
 type Reference struct {
+	Ref string `json:"$ref"`
+
+	// The definition of the component, if it was extracted from a type
+	// implementing IsComponent.
+	definition *Spec
+}
+
+func Ref(to string) Reference {
+	return Reference{
+		Ref:        to,
+		definition: nil,
+	}
 }
 
 func (Reference) parameter() {
@@ -168,15 +341,64 @@ func (Reference) parameter() {
 
 var _ Parameter = Reference{}
 
+// The name of the component, e.g. `RequestId` for `#/components/parameters/RequestId`.
+func (r Reference) Name() string {
+	return strings.TrimPrefix(r.Ref, componentPrefix)
+}
+
+// The definition of the component, if known.
+func (r Reference) Definition() (Spec, bool) {
+	if r.definition == nil {
+		return Spec{}, false
+	}
+	return *r.definition, true
+}
+
+const componentPrefix = "#/components/parameters/"
+
+// The names of components, as mandated by the spec.
+var componentNameRegex = regexp.MustCompile(`^[a-zA-Z0-9.\-_]+$`)
+
+// Implement this interface on the type of a parameter to document
+// it once, in `components.parameters`, and refer to it from each
+// operation, e.g. for a header `X-Request-Id` used by all endpoints.
+type IsComponent interface {
+	// The name of the component, e.g. "RequestId".
+	ParameterComponent() string
+}
+
+// Return the definition of a parameter, following a reference if necessary.
+//
+// Returns `false` for references whose definition is unknown.
+func Resolve(param Parameter, components map[string]Parameter) (Spec, bool) {
+	switch param := param.(type) {
+	case Spec:
+		return param, true
+	case Reference:
+		if spec, ok := param.Definition(); ok {
+			return spec, true
+		}
+		// Components are not expected to refer to other components.
+		if next, ok := components[param.Name()]; ok {
+			return Resolve(next, nil)
+		}
+	}
+	return Spec{}, false
+}
+
 type SchemaSpec struct {
-	schema.Schema `flatten:""`
+	Schema schema.Schema `json:"schema"`
 
 	// Describes how the parameter value will be serialized depending on the type of the parameter value. Default values (based on value of in): for "query" - "form"; for "path" - "simple"; for "header" - "simple"; for "cookie" - "form".
-	Style *string `json:"style,omitempty"`
+	Style *Style `json:"style,omitempty"`
 
 	// When this is true, parameter values of type array or object generate separate parameters for each value of the array or key-value pair of the map. For other types of parameters this field has no effect. When style is "form", the default value is true. For all other styles, the default value is false. Note that despite false being the default for deepObject, the combination of false with deepObject is undefined.
 	Explode *bool `json:"explode,omitempty"`
 
+	// If true, reserved characters, e.g. `/` or `?`, are sent without percent-encoding.
+	// Query parameters only.
+	AllowReserved bool `json:"allowReserved,omitempty"`
+
 	Example  *shared.Json       `json:"example,omitempty"`
 	Examples *[]example.Example `json:"examples,omitempty"`
 }
@@ -190,5 +412,39 @@ func (s SchemaSpec) MarshalJSON() ([]byte, error) {
 }
 
 type ContentSpec struct {
+	// A map containing exactly one media type, e.g. `application/json`.
 	Content map[string]media.Type `json:"content"`
 }
+
+// Document a field serialized as a document of type `contentType`, e.g. a JSON-encoded object.
+//
+// The schema describes the document, so nested fields use their `json` tags.
+func contentFromField(container reflect.Type, from reflect.StructField, contentType string, report *diagnostic.Report, comments doc.Comments) (ContentSpec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ContentSpec{}, fmt.Errorf("invalid content type \"%s\": %w", contentType, err)
+	}
+	if !strings.Contains(mediaType, "/") {
+		return ContentSpec{}, fmt.Errorf("invalid content type \"%s\", expected e.g. \"application/json\"", contentType)
+	}
+	schemaImpl, err := schema.ImplementationFromFieldOf(container, from, "json", comments)
+	if err != nil {
+		return ContentSpec{}, err
+	}
+	schemaImpl.Diagnostics = report
+	schemaImpl.Description = nil
+	schemaImpl.Deprecated = false
+	schema, err := schema.FromImplementation(schemaImpl)
+	if err != nil {
+		return ContentSpec{}, err
+	}
+	return ContentSpec{
+		Content: map[string]media.Type{
+			mediaType: {
+				Schema:   &schema,
+				Example:  nil,
+				Examples: nil,
+			},
+		},
+	}, nil
+}
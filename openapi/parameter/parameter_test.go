@@ -1,9 +1,11 @@
 package parameter_test
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/parameter"
 	"github.com/pasqal-io/gousset/testutils"
 	"gotest.tools/assert"
@@ -173,3 +175,268 @@ func TestParameterWithDescriptionAndPublicName(t *testing.T) {
 		}
 		]`)
 }
+
+// Defaults are emitted in the schema, typed per the field, and fields initialized
+// by a method are documented as such.
+type PageQuery struct {
+	Page    int    `query:"page" description:"The page" default:"1"`
+	Verbose bool   `query:"verbose" description:"More details" default:"false"`
+	Sort    string `query:"sort" description:"The sort order" orMethod:"DefaultSort"`
+}
+
+func TestParameterDefaults(t *testing.T) {
+	spec, err := parameter.FromStruct(reflect.TypeFor[PageQuery](), parameter.InQuery)
+	assert.NilError(t, err)
+
+	testutils.EqualJSON(t, spec, `[
+		{
+			"description": "The page",
+			"in": "query",
+			"name": "page",
+			"schema": {
+				"type": "number",
+				"format": "int32",
+				"default": 1
+			}
+		},
+		{
+			"description": "More details",
+			"in": "query",
+			"name": "verbose",
+			"schema": {
+				"type": "boolean",
+				"default": false
+			}
+		},
+		{
+			"description": "The sort order\n\nIf unspecified, a default value is computed by the server.",
+			"in": "query",
+			"name": "sort",
+			"schema": {
+				"type": "string"
+			}
+		}
+	]`)
+}
+
+type DefaultPath struct {
+	Id string `path:"id" description:"The id" default:"me"`
+}
+
+// Path parameters are always required, even with a default value.
+func TestPathParameterRequired(t *testing.T) {
+	spec, err := parameter.FromStruct(reflect.TypeFor[DefaultPath](), parameter.InPath)
+	assert.NilError(t, err)
+
+	testutils.EqualJSON(t, spec, `[
+		{
+			"description": "The id",
+			"in": "path",
+			"name": "id",
+			"required": true,
+			"schema": {
+				"type": "string",
+				"default": "me"
+			}
+		}
+	]`)
+}
+
+type Filter struct {
+	Name string `query:"name"`
+	Role string `query:"role"`
+}
+
+type StyledQuery struct {
+	Ids    []int    `query:"ids" description:"Comma-separated ids" explode:"false"`
+	Tags   []string `query:"tags" description:"Pipe-separated tags" style:"pipeDelimited"`
+	Filter Filter   `query:"filter" description:"Filter" style:"deepObject" explode:"true"`
+	Next   string   `query:"next" description:"A URL" allowReserved:"" allowEmptyValue:""`
+}
+
+func TestParameterStyles(t *testing.T) {
+	spec, err := parameter.FromStruct(reflect.TypeFor[StyledQuery](), parameter.InQuery)
+	assert.NilError(t, err)
+
+	testutils.EqualJSON(t, spec, `[
+		{
+			"name": "ids",
+			"in": "query",
+			"description": "Comma-separated ids",
+			"required": true,
+			"explode": false,
+			"schema": {
+				"type": "array",
+				"items": {"type": "number", "format": "int32"}
+			}
+		},
+		{
+			"name": "tags",
+			"in": "query",
+			"description": "Pipe-separated tags",
+			"required": true,
+			"style": "pipeDelimited",
+			"schema": {
+				"type": "array",
+				"items": {"type": "string"}
+			}
+		},
+		{
+			"name": "filter",
+			"in": "query",
+			"description": "Filter",
+			"required": true,
+			"style": "deepObject",
+			"explode": true,
+			"schema": {
+				"type": "object",
+				"required": ["name", "role"],
+				"properties": {
+					"name": {"type": "string"},
+					"role": {"type": "string"}
+				}
+			}
+		},
+		{
+			"name": "next",
+			"in": "query",
+			"description": "A URL",
+			"required": true,
+			"allowEmptyValue": true,
+			"allowReserved": true,
+			"schema": {"type": "string"}
+		}
+	]`)
+}
+
+func TestParameterInvalidStyles(t *testing.T) {
+	for message, typ := range map[string]reflect.Type{
+		"unknown style \"csv\"": reflect.TypeFor[struct {
+			Ids []int `query:"ids" description:"Ids" style:"csv"`
+		}](),
+		"style label may not be used for query parameters": reflect.TypeFor[struct {
+			Ids []int `query:"ids" description:"Ids" style:"label"`
+		}](),
+		"style spaceDelimited may only be used for parameters of type array, got string": reflect.TypeFor[struct {
+			Name string `query:"name" description:"Name" style:"spaceDelimited"`
+		}](),
+		"style deepObject may not be used with explode=false": reflect.TypeFor[struct {
+			Filter Filter `query:"filter" description:"Filter" style:"deepObject" explode:"false"`
+		}](),
+		"error while attempting to parse tag explode as boolean": reflect.TypeFor[struct {
+			Ids []int `query:"ids" description:"Ids" explode:"sometimes"`
+		}](),
+	} {
+		_, err := parameter.FromStruct(typ, parameter.InQuery)
+		assert.ErrorContains(t, err, message)
+	}
+	_, err := parameter.FromStruct(reflect.TypeFor[struct {
+		Id string `path:"id" description:"Id" allowReserved:""`
+	}](), parameter.InPath)
+	assert.ErrorContains(t, err, "allowReserved may only be used for query parameters")
+	_, err = parameter.FromStruct(reflect.TypeFor[struct {
+		Id string `header:"X-Id" description:"Id" allowEmptyValue:""`
+	}](), parameter.InHeader)
+	assert.ErrorContains(t, err, "allowEmptyValue may only be used for query parameters")
+}
+
+type Criteria struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+type ContentQuery struct {
+	Filter Criteria `query:"filter" description:"A JSON-encoded filter" content:"application/json"`
+}
+
+func TestParameterContent(t *testing.T) {
+	spec, err := parameter.FromStruct(reflect.TypeFor[ContentQuery](), parameter.InQuery)
+	assert.NilError(t, err)
+
+	testutils.EqualJSON(t, spec, `[
+		{
+			"name": "filter",
+			"in": "query",
+			"description": "A JSON-encoded filter",
+			"required": true,
+			"content": {
+				"application/json": {
+					"schema": {
+						"type": "object",
+						"required": ["name", "limit"],
+						"properties": {
+							"limit": {"type": "number", "format": "int32"},
+							"name": {"type": "string"}
+						}
+					}
+				}
+			}
+		}
+	]`)
+
+	for message, typ := range map[string]reflect.Type{
+		"style may not be used with content": reflect.TypeFor[struct {
+			Filter Criteria `query:"filter" description:"Filter" content:"application/json" style:"deepObject"`
+		}](),
+		"invalid content type \"json\"": reflect.TypeFor[struct {
+			Filter Criteria `query:"filter" description:"Filter" content:"json"`
+		}](),
+	} {
+		_, err := parameter.FromStruct(typ, parameter.InQuery)
+		assert.ErrorContains(t, err, message)
+	}
+	_, err = parameter.FromStruct(reflect.TypeFor[struct {
+		Filter Criteria `path:"filter" description:"Filter" content:"application/json"`
+	}](), parameter.InPath)
+	assert.ErrorContains(t, err, "content may only be used for query and header parameters")
+
+	// Schema and content are mutually exclusive.
+	both := spec[0].(parameter.Spec)
+	both.SchemaSpec = &parameter.SchemaSpec{Schema: (*both.ContentSpec.Content["application/json"].Schema)}
+	_, err = json.Marshal(both)
+	assert.ErrorContains(t, err, "schema and content are mutually exclusive")
+}
+
+// Header names are canonicalized and reserved headers are skipped.
+func TestParameterHeaders(t *testing.T) {
+	type Headers struct {
+		RequestId     string `description:"The id of the request"`
+		Tenant        string `header:"x-tenant" description:"The tenant"`
+		Authorization string `header:"authorization" description:"The credentials"`
+		ContentType   string `header:"Content-Type" description:"The type of the body"`
+	}
+	report := diagnostic.NewReport()
+	spec, err := parameter.FromImplementation(parameter.Implementation{Type: reflect.TypeFor[Headers](), In: parameter.InHeader, Diagnostics: report})
+	assert.NilError(t, err)
+	var skipped []string
+	for _, d := range report.Diagnostics() {
+		if d.Code == diagnostic.CodeReservedHeader {
+			skipped = append(skipped, d.Field)
+		}
+	}
+	assert.DeepEqual(t, skipped, []string{"Authorization", "ContentType"})
+
+	testutils.EqualJSON(t, spec, `[
+		{
+			"name": "Request-Id",
+			"in": "header",
+			"description": "The id of the request",
+			"required": true,
+			"schema": {"type": "string"}
+		},
+		{
+			"name": "X-Tenant",
+			"in": "header",
+			"description": "The tenant",
+			"required": true,
+			"schema": {"type": "string"}
+		}
+	]`)
+
+	_, err = parameter.FromStruct(reflect.TypeFor[struct {
+		Tenant      string `header:"X-Tenant" description:"The tenant"`
+		OtherTenant string `header:"x-tenant" description:"The tenant, again"`
+	}](), parameter.InHeader)
+	assert.ErrorContains(t, err, "fields Tenant and OtherTenant of type struct")
+	assert.ErrorContains(t, err, "both define header X-Tenant")
+}
@@ -0,0 +1,105 @@
+package parameter
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pasqal-io/gousset/openapi/schema"
+)
+
+// How the value of a parameter is serialized.
+//
+// See https://spec.openapis.org/oas/v3.0.1.html#style-values
+type Style string
+
+const (
+	// `;id=5`, `;id=3,4,5`, `;id=3;id=4;id=5` (exploded). Path only.
+	StyleMatrix = Style("matrix")
+
+	// `.5`, `.3.4.5`. Path only.
+	StyleLabel = Style("label")
+
+	// `id=5`, `id=3,4,5`, `id=3&id=4&id=5` (exploded). Default for query and cookie.
+	StyleForm = Style("form")
+
+	// `5`, `3,4,5`. Default for path and header.
+	StyleSimple = Style("simple")
+
+	// `id=3%204%205`. Query arrays only.
+	StyleSpaceDelimited = Style("spaceDelimited")
+
+	// `id=3|4|5`. Query arrays only.
+	StylePipeDelimited = Style("pipeDelimited")
+
+	// `id[role]=admin&id[name]=Alex`. Query objects only.
+	StyleDeepObject = Style("deepObject")
+)
+
+// The locations at which each style may be used.
+var styleLocations = map[Style][]In{
+	StyleMatrix:         {InPath},
+	StyleLabel:          {InPath},
+	StyleForm:           {InQuery, InCookie},
+	StyleSimple:         {InPath, InHeader},
+	StyleSpaceDelimited: {InQuery},
+	StylePipeDelimited:  {InQuery},
+	StyleDeepObject:     {InQuery},
+}
+
+// The types to which some styles are restricted.
+var styleTypes = map[Style]schema.Type{
+	StyleSpaceDelimited: schema.TypeArray,
+	StylePipeDelimited:  schema.TypeArray,
+	StyleDeepObject:     schema.TypeObject,
+}
+
+// The style used if none is specified.
+func DefaultStyle(in In) Style {
+	switch in {
+	case InQuery, InCookie:
+		return StyleForm
+	default:
+		return StyleSimple
+	}
+}
+
+// The value of `explode` used if none is specified.
+func DefaultExplode(style Style) bool {
+	return style == StyleForm
+}
+
+// The JSON type declared by a schema, if any.
+func typeOf(s schema.Schema) schema.Type {
+	switch typed := s.(type) {
+	case schema.Primitive:
+		return typed.Type
+	case schema.Object:
+		return typed.Type
+	case schema.Array:
+		return typed.Type
+	}
+	return ""
+}
+
+// Check that the serialization of a parameter is consistent with its location and type.
+func (s SchemaSpec) validate(in In) error {
+	if s.Style != nil {
+		locations, ok := styleLocations[*s.Style]
+		if !ok {
+			return fmt.Errorf("unknown style \"%s\"", *s.Style)
+		}
+		if !slices.Contains(locations, in) {
+			return fmt.Errorf("style %s may not be used for %s parameters", *s.Style, in)
+		}
+		if expected, ok := styleTypes[*s.Style]; ok && typeOf(s.Schema) != expected {
+			return fmt.Errorf("style %s may only be used for parameters of type %s, got %s", *s.Style, expected, typeOf(s.Schema))
+		}
+		if *s.Style == StyleDeepObject && s.Explode != nil && !*s.Explode {
+			return fmt.Errorf("style %s may not be used with explode=false", *s.Style)
+		}
+	}
+	if s.AllowReserved && in != InQuery {
+		return fmt.Errorf("allowReserved may only be used for query parameters")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pasqal-io/gousset/openapi/callback"
+	"github.com/pasqal-io/gousset/openapi/parameter"
+	"github.com/pasqal-io/gousset/openapi/path"
+)
+
+// Walk through a spec, collecting the definitions of the parameters
+// that are documented as components.
+type parameterCollector struct {
+	components map[string]parameter.Parameter
+
+	// The serialized definition of each component, to detect conflicts.
+	canonical map[string]string
+
+	// Where each component was first found, for error messages.
+	origin map[string]string
+}
+
+func (c *parameterCollector) collectParameters(params []parameter.Parameter, location string) error {
+	for i, param := range params {
+		ref, ok := param.(parameter.Reference)
+		if !ok {
+			continue
+		}
+		definition, ok := ref.Definition()
+		if !ok {
+			// A reference provided by the user, hopefully to a component provided by the user.
+			continue
+		}
+		buf, err := json.Marshal(definition)
+		if err != nil {
+			return fmt.Errorf("failed to serialize component parameter %s: %w", ref.Name(), err)
+		}
+		here := appendPointer(location, fmt.Sprint(i))
+		name := ref.Name()
+		if previous, ok := c.canonical[name]; ok {
+			if previous != string(buf) {
+				return fmt.Errorf("conflicting definitions of component parameter %s at %s and %s", name, c.origin[name], here)
+			}
+			continue
+		}
+		c.components[name] = definition
+		c.canonical[name] = string(buf)
+		c.origin[name] = here
+	}
+	return nil
+}
+
+func (c *parameterCollector) collectPath(spec path.Spec, location string) error {
+	if spec.Parameters != nil {
+		if err := c.collectParameters(*spec.Parameters, appendPointer(location, "parameters")); err != nil {
+			return err
+		}
+	}
+	operations := spec.Operations()
+	for _, verb := range sortedKeys(operations) {
+		op := operations[verb]
+		here := appendPointer(location, string(verb))
+		if err := c.collectParameters(op.Parameters, appendPointer(here, "parameters")); err != nil {
+			return err
+		}
+		if op.Callbacks == nil {
+			continue
+		}
+		for _, name := range sortedKeys(*op.Callbacks) {
+			spec, ok := (*op.Callbacks)[name].(callback.Spec)
+			if !ok {
+				continue
+			}
+			for _, template := range sortedKeys(spec) {
+				if item, ok := spec[template].(path.Spec); ok {
+					if err := c.collectPath(item, appendPointer(here, "callbacks", name, template)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Add to `components.parameters` the definitions of the parameters
+// whose types implement `parameter.IsComponent`.
+func collectParameterComponents(spec *Spec) error {
+	c := parameterCollector{
+		components: make(map[string]parameter.Parameter),
+		canonical:  make(map[string]string),
+		origin:     make(map[string]string),
+	}
+	for _, route := range sortedKeys(spec.Paths) {
+		if err := c.collectPath(spec.Paths[route], appendPointer("/paths", string(route))); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedKeys(spec.Webhooks) {
+		if err := c.collectPath(spec.Webhooks[name], appendPointer("/webhooks", name)); err != nil {
+			return err
+		}
+	}
+	if len(c.components) != 0 {
+		spec.Components.Parameters = &c.components
+	}
+	return nil
+}
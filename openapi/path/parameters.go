@@ -0,0 +1,155 @@
+package path
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
+	"github.com/pasqal-io/gousset/openapi/doc"
+	"github.com/pasqal-io/gousset/openapi/parameter"
+)
+
+// A parameter is identified by its location and name.
+type parameterKey struct {
+	in   parameter.In
+	name string
+}
+
+// A parameter, with its identity and a canonical representation for comparison.
+type keyedParameter struct {
+	key       parameterKey
+	canonical string
+	parameter parameter.Parameter
+}
+
+func makeKeyedParameter(param parameter.Parameter) (keyedParameter, bool, error) {
+	spec, ok := parameter.Resolve(param, nil)
+	if !ok {
+		// References to unknown components cannot be compared.
+		return keyedParameter{}, false, nil
+	}
+	buf, err := json.Marshal(param)
+	if err != nil {
+		return keyedParameter{}, false, fmt.Errorf("failed to serialize parameter %s: %w", spec.Name, err)
+	}
+	return keyedParameter{
+		key:       parameterKey{in: spec.In, name: spec.Name},
+		canonical: string(buf),
+		parameter: param,
+	}, true, nil
+}
+
+// Compile the parameters shared by all verbs of a path.
+func sharedParametersFromType(typ reflect.Type, report *diagnostic.Report, comments doc.Comments) ([]parameter.Parameter, error) {
+	if typ == nil || typ.Kind() == reflect.Invalid {
+		return nil, nil
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid type %s for shared parameters, expected a struct", typ.String())
+	}
+	var result []parameter.Parameter
+	var errs []error
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		at := diagnostic.Error{Type: typ.String(), Field: field.Name}
+		var in parameter.In
+		switch field.Name {
+		case "Path":
+			in = parameter.InPath
+		case "Query":
+			in = parameter.InQuery
+		case "Header":
+			in = parameter.InHeader
+		default:
+			at.Err = fmt.Errorf("invalid type %s for shared parameters, it may not have fields other than Path, Query, Header, found %s", typ.String(), field.Name)
+			errs = append(errs, at)
+			continue
+		}
+		params, err := parameter.FromImplementation(parameter.Implementation{Type: field.Type, In: in, Diagnostics: report, Comments: comments})
+		if err != nil {
+			errs = append(errs, diagnostic.Locate(err, at))
+		}
+		result = append(result, params...)
+	}
+	return result, diagnostic.Join(errs...)
+}
+
+// Move to path level the parameters that are identical in all operations.
+//
+// Operations that restate a shared parameter identically are simplified.
+// Parameters that operations define differently are left on each operation.
+func factorizeParameters(spec *Spec, shared []parameter.Parameter) error {
+	operations := spec.Operations()
+	verbs := slices.Sorted(maps.Keys(operations))
+
+	// Parameters defined identically by all operations are shared, if there are several operations.
+	var hoisted []parameter.Parameter
+	explicit := make(map[parameterKey]string)
+	for _, param := range shared {
+		keyed, ok, err := makeKeyedParameter(param)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := explicit[keyed.key]; ok {
+			return fmt.Errorf("duplicate shared %s parameter %s", keyed.key.in, keyed.key.name)
+		}
+		explicit[keyed.key] = keyed.canonical
+	}
+	if len(verbs) >= 2 {
+		for _, param := range operations[verbs[0]].Parameters {
+			keyed, ok, err := makeKeyedParameter(param)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if _, ok := explicit[keyed.key]; ok {
+				// Operations override the explicit definition.
+				continue
+			}
+			everywhere := true
+			for _, verb := range verbs[1:] {
+				if !slices.ContainsFunc(operations[verb].Parameters, func(other parameter.Parameter) bool {
+					otherKeyed, ok, _ := makeKeyedParameter(other)
+					return ok && otherKeyed.canonical == keyed.canonical
+				}) {
+					everywhere = false
+					break
+				}
+			}
+			if everywhere {
+				hoisted = append(hoisted, param)
+				explicit[keyed.key] = keyed.canonical
+			}
+		}
+	}
+
+	// Remove from operations the parameters that are now shared.
+	for _, op := range operations {
+		var remaining []parameter.Parameter
+		for _, param := range op.Parameters {
+			keyed, ok, err := makeKeyedParameter(param)
+			if err != nil {
+				return err
+			}
+			if ok && explicit[keyed.key] == keyed.canonical {
+				continue
+			}
+			remaining = append(remaining, param)
+		}
+		op.Parameters = remaining
+	}
+
+	all := append(slices.Clone(shared), hoisted...)
+	if len(all) != 0 {
+		spec.Parameters = &all
+	}
+	return nil
+}
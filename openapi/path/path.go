@@ -2,16 +2,23 @@ package path
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/iancoleman/strcase"
+	"github.com/pasqal-io/gousset/openapi/callback"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
+	"github.com/pasqal-io/gousset/openapi/expression"
 	"github.com/pasqal-io/gousset/openapi/operation"
 	"github.com/pasqal-io/gousset/openapi/parameter"
 	"github.com/pasqal-io/gousset/openapi/response"
+	"github.com/pasqal-io/gousset/openapi/schema"
 	"github.com/pasqal-io/gousset/openapi/security"
+	"github.com/pasqal-io/gousset/shared"
 )
 
 // A path in the API.
@@ -19,18 +26,244 @@ import (
 // MUST start with `/`. Path templating is allowed.
 type Route string
 
-var routeTemplateRegex = regexp.MustCompile("/:([^/]*)")
+// A syntax for captures in paths.
+type CaptureSyntax string
 
+const (
+	// `/:name`, e.g. httprouter, gin, echo.
+	CaptureColon = CaptureSyntax(":")
+
+	// `/{name}`, `/{name...}` or `/{name:regex}`, e.g. net/http, gorilla/mux, chi.
+	CaptureBraces = CaptureSyntax("{")
+
+	// `/<name>`.
+	CaptureAngle = CaptureSyntax("<")
+
+	// `/*name`, matching the rest of the path, e.g. httprouter, gin.
+	CaptureStar = CaptureSyntax("*")
+)
+
+// A conversion applied to the names of captures.
+type Case string
+
+const (
+	// Keep names unchanged, e.g. `:userId` becomes `{userId}`.
+	CasePreserve = Case("")
+
+	// e.g. `:userId` becomes `{user_id}`.
+	CaseSnake = Case("snake")
+
+	// e.g. `:user_id` becomes `{userId}`.
+	CaseLowerCamel = Case("lowerCamel")
+
+	// e.g. `:userId` becomes `{user-id}`.
+	CaseKebab = Case("kebab")
+)
+
+// Options for converting paths into routes.
+type RouteOptions struct {
+	// The syntaxes of captures to recognize.
+	//
+	// If unspecified, all of them.
+	Syntaxes []CaptureSyntax `exhaustruct:"optional"`
+
+	// The conversion applied to the names of captures.
+	//
+	// If unspecified, names are preserved.
+	Case Case `exhaustruct:"optional"`
+}
+
+func (o RouteOptions) accepts(syntax CaptureSyntax) bool {
+	return len(o.Syntaxes) == 0 || slices.Contains(o.Syntaxes, syntax)
+}
+
+func (o RouteOptions) convert(name string) (string, error) {
+	switch o.Case {
+	case CasePreserve:
+		return name, nil
+	case CaseSnake:
+		return strcase.ToSnake(name), nil
+	case CaseLowerCamel:
+		return strcase.ToLowerCamel(name), nil
+	case CaseKebab:
+		return strcase.ToKebab(name), nil
+	default:
+		return "", fmt.Errorf("unknown case conversion \"%s\"", o.Case)
+	}
+}
+
+// A template variable of a route.
+type Capture struct {
+	// The name of the variable, after case conversion.
+	Name string
+
+	// If specified, a regular expression that the value must match, e.g. `^[0-9]+$`.
+	Pattern *string
+
+	// If true, the variable matches the rest of the path, including slashes,
+	// e.g. `{name...}` or `*name`.
+	Rest bool
+}
+
+// Convert a path into a route, with the default options.
 func MakeRoute(path string) (Route, error) {
+	route, _, err := ParseRoute(path, RouteOptions{})
+	return route, err
+}
+
+// Convert a path into a route, e.g. `/users/:id` into `/users/{id}`, and
+// list its captures.
+func ParseRoute(path string, options RouteOptions) (Route, []Capture, error) {
 	if !strings.HasPrefix(path, "/") {
-		return "<error>", fmt.Errorf("expected a path, starting with '/', got \"%s\"", path)
+		return "<error>", nil, fmt.Errorf("expected a path, starting with '/', got \"%s\"", path)
+	}
+	var captures []Capture
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		var name string
+		var pattern *string
+		rest := false
+		switch {
+		case segment == "":
+			continue
+		case segment[0] == ':' && options.accepts(CaptureColon):
+			name = segment[1:]
+		case segment[0] == '*' && options.accepts(CaptureStar):
+			name = segment[1:]
+			rest = true
+		case segment[0] == '<' && strings.HasSuffix(segment, ">") && options.accepts(CaptureAngle):
+			name = segment[1 : len(segment)-1]
+		case segment[0] == '{' && strings.HasSuffix(segment, "}") && options.accepts(CaptureBraces):
+			name = segment[1 : len(segment)-1]
+			if before, regex, ok := strings.Cut(name, ":"); ok {
+				if _, err := regexp.Compile(regex); err != nil {
+					return "<error>", nil, fmt.Errorf("invalid capture \"%s\" in path \"%s\": %w", segment, path, err)
+				}
+				name = before
+				pattern = shared.Ptr(fmt.Sprint("^", regex, "$"))
+			} else {
+				name, rest = strings.CutSuffix(name, "...")
+			}
+		default:
+			continue
+		}
+		if name == "" || strings.ContainsAny(name, "{}<>:*") {
+			return "<error>", nil, fmt.Errorf("invalid capture \"%s\" in path \"%s\"", segment, path)
+		}
+		name, err := options.convert(name)
+		if err != nil {
+			return "<error>", nil, err
+		}
+		segments[i] = fmt.Sprint("{", name, "}")
+		captures = append(captures, Capture{
+			Name:    name,
+			Pattern: pattern,
+			Rest:    rest,
+		})
+	}
+	return Route(strings.Join(segments, "/")), captures, nil
+}
+
+// Document the patterns of captures, and whether they match the rest of
+// the path, on the matching path parameters.
+func ApplyCaptures(spec Spec, captures []Capture) error {
+	for _, capture := range captures {
+		if capture.Pattern == nil && !capture.Rest {
+			continue
+		}
+		if spec.Parameters != nil {
+			if err := applyCapture(*spec.Parameters, capture); err != nil {
+				return fmt.Errorf("in shared parameters, %w", err)
+			}
+		}
+		for _, op := range spec.Operations() {
+			if err := applyCapture(op.Parameters, capture); err != nil {
+				return fmt.Errorf("in operation %s, %w", op.OperationId, err)
+			}
+		}
+	}
+	return nil
+}
+
+func applyCapture(params []parameter.Parameter, capture Capture) error {
+	for i, param := range params {
+		paramSpec, ok := param.(parameter.Spec)
+		if !ok || paramSpec.In != parameter.InPath || paramSpec.Name != capture.Name {
+			continue
+		}
+		if capture.Rest {
+			paramSpec.Rest = true
+			params[i] = paramSpec
+		}
+		if capture.Pattern == nil || paramSpec.SchemaSpec == nil {
+			continue
+		}
+		primitive, ok := paramSpec.Schema.(schema.Primitive)
+		if !ok {
+			return fmt.Errorf("path parameter %s is constrained by pattern \"%s\", but it is not a primitive", capture.Name, *capture.Pattern)
+		}
+		if primitive.Pattern != nil && *primitive.Pattern != *capture.Pattern {
+			return fmt.Errorf("path parameter %s has pattern \"%s\", which conflicts with the pattern \"%s\" of the route", capture.Name, *primitive.Pattern, *capture.Pattern)
+		}
+		primitive.Pattern = capture.Pattern
+		schemaSpec := *paramSpec.SchemaSpec
+		schemaSpec.Schema = primitive
+		paramSpec.SchemaSpec = &schemaSpec
+		params[i] = paramSpec
+	}
+	return nil
+}
+
+var templateVariableRegex = regexp.MustCompile(`\{([^{}/]*)\}`)
+
+// The names of the template variables of a route, e.g. `["id"]` for `/users/{id}`.
+func (r Route) Variables() []string {
+	var result []string
+	for _, match := range templateVariableRegex.FindAllStringSubmatch(string(r), -1) {
+		result = append(result, match[1])
+	}
+	return result
+}
+
+// Check that the path parameters of each operation match the
+// template variables of the route, in both directions.
+func CheckParameters(route Route, spec Spec) error {
+	variables := route.Variables()
+	for i, variable := range variables {
+		if slices.Contains(variables[:i], variable) {
+			return fmt.Errorf("route %s has several template variables {%s}", route, variable)
+		}
+	}
+	pathParameters := func(params []parameter.Parameter) []string {
+		var result []string
+		for _, param := range params {
+			if spec, ok := parameter.Resolve(param, nil); ok && spec.In == parameter.InPath {
+				result = append(result, spec.Name)
+			}
+		}
+		return result
+	}
+	var common []string
+	if spec.Parameters != nil {
+		common = pathParameters(*spec.Parameters)
+	}
+	// Errors are collected across operations.
+	var errs []error
+	for _, verb := range slices.Sorted(maps.Keys(spec.Operations())) {
+		op := spec.Operations()[verb]
+		declared := append(pathParameters(op.Parameters), common...)
+		for _, variable := range variables {
+			if !slices.Contains(declared, variable) {
+				errs = append(errs, fmt.Errorf("in operation %s, template variable {%s} of route %s is not declared as a path parameter, expected a `Path` field with tag `path:\"%s\"`", op.OperationId, variable, route, variable))
+			}
+		}
+		for _, name := range declared {
+			if !slices.Contains(variables, name) {
+				errs = append(errs, fmt.Errorf("in operation %s, path parameter %s does not appear in route %s", op.OperationId, name, route))
+			}
+		}
 	}
-	// Convert "/:foo"-style captures to "/{foo}"-style captures.
-	replaced := routeTemplateRegex.ReplaceAllFunc([]byte(path), func(b []byte) []byte {
-		subpath, _ := strings.CutPrefix(string(b), "/:")
-		return []byte(fmt.Sprint("/{", strcase.ToSnake(subpath), "}"))
-	})
-	return Route(replaced), nil
+	return diagnostic.Join(errs...)
 }
 
 // The HTTP verbs.
@@ -44,6 +277,7 @@ const (
 	Options = Verb("options")
 	Patch   = Verb("patch")
 	Head    = Verb("head")
+	Trace   = Verb("trace")
 )
 
 // Specifications for one path (all verbs).
@@ -67,6 +301,32 @@ type Spec struct {
 	Delete  *operation.Spec `json:"delete,omitempty"`
 	Options *operation.Spec `json:"options,omitempty"`
 	Patch   *operation.Spec `json:"patch,omitempty"`
+	Head    *operation.Spec `json:"head,omitempty" exhaustruct:"optional"`
+	Trace   *operation.Spec `json:"trace,omitempty" exhaustruct:"optional"`
+}
+
+func (Spec) IsPathItem() {}
+
+var _ callback.PathItem = Spec{}
+
+// The operations defined on this path, indexed by verb.
+func (s Spec) Operations() map[Verb]*operation.Spec {
+	result := make(map[Verb]*operation.Spec)
+	for verb, op := range map[Verb]*operation.Spec{
+		Get:     s.Get,
+		Put:     s.Put,
+		Post:    s.Post,
+		Delete:  s.Delete,
+		Options: s.Options,
+		Patch:   s.Patch,
+		Head:    s.Head,
+		Trace:   s.Trace,
+	} {
+		if op != nil {
+			result[verb] = op
+		}
+	}
+	return result
 }
 
 // User-provided metadata containing information on the implementation
@@ -75,7 +335,27 @@ type Implementation struct {
 	Summary     string
 	Description *string
 	Path        string
-	PerVerb     map[Verb]VerbImplementation
+
+	// The type of parameters shared by all verbs, e.g. a path parameter.
+	//
+	// This must be either the zero value (no shared parameters) or a struct
+	// containing no other field than `Query`, `Path`, `Header`. Verbs may
+	// override these parameters but cannot remove them.
+	//
+	// Parameters that are identical for all verbs are shared automatically.
+	Parameters reflect.Type `exhaustruct:"optional"`
+
+	PerVerb map[Verb]VerbImplementation
+
+	// Where to report diagnostics. If nil, they are logged.
+	//
+	// Diagnostics are attached to the operation in which they were found.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	//
+	// Inherited by callbacks unless they specify their own.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
 // User-provided metadata containing information on the implementation
@@ -104,16 +384,84 @@ type VerbImplementation struct {
 
 	// If `true`, mark this endpoint as deprecated.
 	Deprecated bool
+
+	// Requests that this endpoint may send to its client, e.g. to
+	// notify that a job is complete, indexed by a unique name.
+	Callbacks map[string]CallbackImplementation `exhaustruct:"optional"`
 }
 
+// User-provided metadata describing a callback.
+//
+// Each key is a runtime expression or a template containing runtime
+// expressions, evaluated against the request and response of the parent
+// operation to obtain the URL of the callback, e.g.
+// `expression.Embed(expression.RequestBody("callbackUrl"))`.
+//
+// The field `Path` of each Implementation is ignored and replaced
+// with the key.
+type CallbackImplementation map[string]Implementation
+
+// Compile the callbacks of the operation `parentId`.
+func fromCallbacks(parentId string, impl map[string]CallbackImplementation, report *diagnostic.Report, comments doc.Comments) (map[string]callback.Callback, error) {
+	result := make(map[string]callback.Callback)
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(impl)) {
+		callbackImpl := impl[name]
+		spec := make(callback.Spec)
+		for _, template := range slices.Sorted(maps.Keys(callbackImpl)) {
+			pathImpl := callbackImpl[template]
+			if err := expression.ValidateTemplate(template); err != nil {
+				errs = append(errs, fmt.Errorf("in callback %s: %w", name, err))
+				continue
+			}
+			pathImpl.Path = template
+			pathImpl.Diagnostics = report.ForEndpoint(name)
+			if pathImpl.Comments == nil {
+				pathImpl.Comments = comments
+			}
+			item, err := FromPath(pathImpl)
+			if err != nil {
+				errs = append(errs, diagnostic.Wrap(err, fmt.Sprint("in callback ", name)))
+				continue
+			}
+			// The id of operations must be unique across the API, including callbacks.
+			for _, op := range item.Operations() {
+				op.OperationId = callbackOperationId(parentId, name, op.OperationId)
+			}
+			spec[template] = item
+		}
+		result[name] = spec
+	}
+	return result, diagnostic.Join(errs...)
+}
+
+// Characters that may not appear in the id of a callback operation.
+var invalidCallbackIdRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// The id of an operation of callback `name` of operation `parentId`, e.g.
+// `post_jobs_onComplete_post_request.body_callbackUrl`.
+//
+// Runtime expressions contain characters such as `{$` or `#` that tools
+// do not expect in an operation id, so they are replaced with `_`.
+func callbackOperationId(parentId string, name string, id string) string {
+	raw := fmt.Sprint(parentId, " ", name, " ", id)
+	return strings.Trim(invalidCallbackIdRegex.ReplaceAllString(raw, "_"), "_")
+}
+
+// Compile the spec of a path.
+//
+// Errors are collected across operations. On error, the operations that
+// could be compiled are returned nonetheless.
 func FromPath(impl Implementation) (Spec, error) {
 	result := Spec{
 		Summary:     impl.Summary,
 		Description: impl.Description,
-		// We do not attempt to factorize shared parameters.
-		Parameters: nil,
+		Parameters:  nil,
 	}
-	for verb, verbImpl := range impl.PerVerb {
+	var errs []error
+	for _, verb := range slices.Sorted(maps.Keys(impl.PerVerb)) {
+		verbImpl := impl.PerVerb[verb]
+		operationReport := impl.Diagnostics.ForEndpoint(operation.MakeId(string(verb), impl.Path))
 		op, err := operation.FromImplementation(operation.Implementation{
 			Input:        verbImpl.Input,
 			Verb:         string(verb),
@@ -124,9 +472,20 @@ func FromPath(impl Implementation) (Spec, error) {
 			ExternalDocs: verbImpl.ExternalDocs,
 			Responses:    verbImpl.Response,
 			Deprecated:   verbImpl.Deprecated,
+			Diagnostics:  operationReport,
+			Comments:     impl.Comments,
 		})
 		if err != nil {
-			return Spec{}, fmt.Errorf("failed to extract specs for operation %s at %s: %w", verb, impl.Path, err)
+			errs = append(errs, err)
+			continue
+		}
+		if len(verbImpl.Callbacks) != 0 {
+			callbacks, err := fromCallbacks(op.OperationId, verbImpl.Callbacks, operationReport, impl.Comments)
+			if err != nil {
+				errs = append(errs, diagnostic.Locate(err, diagnostic.Error{Path: impl.Path, Verb: string(verb)}))
+				continue
+			}
+			op.Callbacks = &callbacks
 		}
 		var ptr **operation.Spec
 		switch verb {
@@ -142,10 +501,22 @@ func FromPath(impl Implementation) (Spec, error) {
 			ptr = &result.Options
 		case Patch:
 			ptr = &result.Patch
+		case Head:
+			ptr = &result.Head
+		case Trace:
+			ptr = &result.Trace
 		default:
-			panic(fmt.Sprint("Verb not handled ", verb))
+			errs = append(errs, diagnostic.Error{Path: impl.Path, Verb: string(verb), Err: fmt.Errorf("unknown verb %s", verb)})
+			continue
 		}
 		*ptr = &op
 	}
-	return result, nil
+	shared, err := sharedParametersFromType(impl.Parameters, impl.Diagnostics.ForEndpoint(impl.Path), impl.Comments)
+	if err != nil {
+		errs = append(errs, diagnostic.Locate(diagnostic.Wrap(err, "failed to extract shared parameters"), diagnostic.Error{Path: impl.Path}))
+	}
+	if err := factorizeParameters(&result, shared); err != nil {
+		errs = append(errs, fmt.Errorf("invalid parameters at %s: %w", impl.Path, err))
+	}
+	return result, diagnostic.Join(errs...)
 }
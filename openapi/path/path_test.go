@@ -1,10 +1,14 @@
 package path_test
 
 import (
+	"maps"
 	"reflect"
+	"slices"
 	"testing"
 
+	"github.com/pasqal-io/gousset/openapi/expression"
 	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/shared/structs"
 	"github.com/pasqal-io/gousset/testutils"
 	"gotest.tools/assert"
 )
@@ -22,10 +26,44 @@ func TestMakeRoute(t *testing.T) {
 
 // Test that /:FooBar-style captures are converted properly.
 func TestMakeRouteConvertsCaptures(t *testing.T) {
-	path, err := path.MakeRoute("/:FOO/:bar/:SnaFu/UPPER_NORMAL/lower_normal/CamelNormal")
+	route, err := path.MakeRoute("/:FOO/:bar/:SnaFu/UPPER_NORMAL/lower_normal/CamelNormal")
 	assert.NilError(t, err)
 
-	assert.Equal(t, string(path), "/{foo}/{bar}/{sna_fu}/UPPER_NORMAL/lower_normal/CamelNormal")
+	assert.Equal(t, string(route), "/{FOO}/{bar}/{SnaFu}/UPPER_NORMAL/lower_normal/CamelNormal")
+
+	route, _, err = path.ParseRoute("/:FOO/:bar/:SnaFu/UPPER_NORMAL/lower_normal/CamelNormal", path.RouteOptions{Case: path.CaseSnake})
+	assert.NilError(t, err)
+
+	assert.Equal(t, string(route), "/{foo}/{bar}/{sna_fu}/UPPER_NORMAL/lower_normal/CamelNormal")
+}
+
+// Test the syntaxes of captures.
+func TestParseRoute(t *testing.T) {
+	route, captures, err := path.ParseRoute("/a/:colon/{braces}/<angle>/{regex:[0-9]+}/{name}.json/*rest", path.RouteOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, string(route), "/a/{colon}/{braces}/{angle}/{regex}/{name}.json/{rest}")
+	testutils.EqualJSON(t, captures, `[
+		{"Name": "colon", "Pattern": null, "Rest": false},
+		{"Name": "braces", "Pattern": null, "Rest": false},
+		{"Name": "angle", "Pattern": null, "Rest": false},
+		{"Name": "regex", "Pattern": "^[0-9]+$", "Rest": false},
+		{"Name": "rest", "Pattern": null, "Rest": true}
+	]`)
+
+	// Only the requested syntaxes are converted.
+	route, _, err = path.ParseRoute("/:userId/<user_id>/*file_path/{path...}", path.RouteOptions{
+		Syntaxes: []path.CaptureSyntax{path.CaptureAngle, path.CaptureBraces},
+		Case:     path.CaseLowerCamel,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, string(route), "/:userId/{userId}/*file_path/{path}")
+
+	_, _, err = path.ParseRoute("/users/:", path.RouteOptions{})
+	assert.ErrorContains(t, err, "invalid capture \":\"")
+	_, _, err = path.ParseRoute("/users/{id:[0-9}", path.RouteOptions{})
+	assert.ErrorContains(t, err, "invalid capture \"{id:[0-9}\"")
+	_, _, err = path.ParseRoute("/users/:id", path.RouteOptions{Case: "SHOUTING"})
+	assert.ErrorContains(t, err, "unknown case conversion")
 }
 
 // Test that we can compile the OpenAPI Spec from a fairly simple implementation.
@@ -79,36 +117,36 @@ func TestFromPath(t *testing.T) {
 	}
 	testutils.EqualJSON(t, result, `{
 		"summary": "Clearly, this is a path",
+		"parameters": [
+		{
+			"description": "expecting a few strings",
+			"in": "path",
+			"name": "some_strings",
+			"required": true,
+			"schema": {
+			"items": {
+				"type": "string"
+			},
+			"type": "array"
+			}
+		},
+		{
+			"description": "expecting a few integers",
+			"in": "query",
+			"name": "query_numbers",
+			"required": true,
+			"schema": {
+			"items": {
+				"type": "number",
+				"format": "int32"
+			},
+			"type": "array"
+			}
+		}
+		],
 		"get": {
 			"summary": "",
 			"operationId": "get /foo/bar",
-			"parameters": [
-			{
-				"description": "expecting a few strings",
-				"in": "path",
-				"name": "some_strings",
-				"required": true,
-				"schema": {
-				"items": {
-					"type": "string"
-				},
-				"type": "array"
-				}
-			},
-			{
-				"description": "expecting a few integers",
-				"in": "query",
-				"name": "query_numbers",
-				"required": true,
-				"schema": {
-				"items": {
-					"type": "number",
-					"format": "int32"
-				},
-				"type": "array"
-				}
-			}
-			],
 			"responses": {
 			"default": {
 				"description": ""
@@ -118,33 +156,6 @@ func TestFromPath(t *testing.T) {
 		"post": {
 			"summary": "",
 			"operationId": "post /foo/bar",
-			"parameters": [
-			{
-				"description": "expecting a few strings",
-				"in": "path",
-				"name": "some_strings",
-				"required": true,
-				"schema": {
-				"items": {
-					"type": "string"
-				},
-				"type": "array"
-				}
-			},
-			{
-				"description": "expecting a few integers",
-				"in": "query",
-				"name": "query_numbers",
-				"required": true,
-				"schema": {
-				"items": {
-					"type": "number",
-					"format": "int32"
-				},
-				"type": "array"
-				}
-			}
-			],
 			"requestBody": {
 			"required": true,
 			"content": {
@@ -157,6 +168,7 @@ func TestFromPath(t *testing.T) {
 					],
 					"properties": {
 					"an_object": {
+						"description": "expecting an object",
 						"type": "object",
 						"required": [
 						"an_int",
@@ -173,6 +185,7 @@ func TestFromPath(t *testing.T) {
 						}
 					},
 					"some_numbers": {
+						"description": "expecting a few integers",
 						"type": "array",
 						"items": {
 						"type": "number",
@@ -193,33 +206,6 @@ func TestFromPath(t *testing.T) {
 		"patch": {
 			"summary": "",
 			"operationId": "patch /foo/bar",
-			"parameters": [
-			{
-				"description": "expecting a few strings",
-				"in": "path",
-				"name": "some_strings",
-				"required": true,
-				"schema": {
-				"items": {
-					"type": "string"
-				},
-				"type": "array"
-				}
-			},
-			{
-				"description": "expecting a few integers",
-				"in": "query",
-				"name": "query_numbers",
-				"required": true,
-				"schema": {
-				"items": {
-					"type": "number",
-					"format": "int32"
-				},
-				"type": "array"
-				}
-			}
-			],
 			"requestBody": {
 			"required": true,
 			"content": {
@@ -232,6 +218,7 @@ func TestFromPath(t *testing.T) {
 					],
 					"properties": {
 					"an_object": {
+						"description": "expecting an object",
 						"type": "object",
 						"required": [
 						"an_int",
@@ -248,6 +235,7 @@ func TestFromPath(t *testing.T) {
 						}
 					},
 					"some_numbers": {
+						"description": "expecting a few integers",
 						"type": "array",
 						"items": {
 						"type": "number",
@@ -267,3 +255,245 @@ func TestFromPath(t *testing.T) {
 		}
 	}`)
 }
+
+type JobBody struct {
+	CallbackUrl string `json:"callbackUrl"`
+}
+
+type JobStatus struct {
+	Status string `json:"status"`
+}
+
+func TestFromPathVerbs(t *testing.T) {
+	result, err := path.FromPath(path.Implementation{
+		Path: "/health",
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			path.Head:  {},
+			path.Trace: {},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, slices.Sorted(maps.Keys(result.Operations())), []path.Verb{path.Head, path.Trace})
+
+	_, err = path.FromPath(path.Implementation{
+		Path: "/health",
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			"connect": {},
+		},
+	})
+	assert.Error(t, err, "in operation connect /health: unknown verb connect")
+}
+
+// Test that callbacks are compiled as path items.
+func TestCallbacks(t *testing.T) {
+	result, err := path.FromPath(path.Implementation{
+		Path: "/jobs",
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			path.Post: {
+				Input: reflect.TypeFor[structs.Body[JobBody]](),
+				Callbacks: map[string]path.CallbackImplementation{
+					"onComplete": {
+						expression.Embed(expression.RequestBody("callbackUrl")): {
+							PerVerb: map[path.Verb]path.VerbImplementation{
+								path.Post: {
+									Input:   reflect.TypeFor[structs.Body[JobStatus]](),
+									Summary: "Notify that the job is complete",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, result.Post.Callbacks, `{
+		"onComplete": {
+			"{$request.body#/callbackUrl}": {
+				"post": {
+					"summary": "Notify that the job is complete",
+					"operationId": "post_jobs_onComplete_post_request.body_callbackUrl",
+					"requestBody": {
+						"required": true,
+						"content": {
+							"application/json": {
+								"schema": {
+									"type": "object",
+									"required": ["status"],
+									"properties": {
+										"status": {"type": "string"}
+									}
+								}
+							}
+						}
+					},
+					"responses": {
+						"default": {
+							"description": ""
+						}
+					}
+				}
+			}
+		}
+	}`)
+}
+
+// Test that callbacks keyed by invalid runtime expressions are rejected.
+func TestCallbacksInvalidExpression(t *testing.T) {
+	_, err := path.FromPath(path.Implementation{
+		Path: "/jobs",
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			path.Post: {
+				Callbacks: map[string]path.CallbackImplementation{
+					"onComplete": {
+						"{$request.cookie.url}": {},
+					},
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "unknown source \"cookie\"")
+}
+
+type UserPath struct {
+	UserId string `path:"user_id" description:"The id of the user"`
+}
+
+// Test that path parameters are checked against the route template.
+func TestCheckParameters(t *testing.T) {
+	check := func(rawPath string, input reflect.Type) error {
+		route, err := path.MakeRoute(rawPath)
+		assert.NilError(t, err)
+		spec, err := path.FromPath(path.Implementation{
+			Path: rawPath,
+			PerVerb: map[path.Verb]path.VerbImplementation{
+				path.Get: {Input: input},
+			},
+		})
+		assert.NilError(t, err)
+		return path.CheckParameters(route, spec)
+	}
+	assert.NilError(t, check("/users/:user_id", reflect.TypeFor[structs.Path[UserPath]]()))
+	assert.NilError(t, check("/users/{user_id}", reflect.TypeFor[structs.Path[UserPath]]()))
+	assert.NilError(t, check("/health", nil))
+
+	assert.Error(t, check("/users/{id}", reflect.TypeFor[structs.Path[UserPath]]()),
+		"in operation get /users/{id}, template variable {id} of route /users/{id} is not declared as a path parameter, expected a `Path` field with tag `path:\"id\"`\n"+
+			"in operation get /users/{id}, path parameter user_id does not appear in route /users/{id}")
+	assert.Error(t, check("/users", reflect.TypeFor[structs.Path[UserPath]]()),
+		"in operation get /users, path parameter user_id does not appear in route /users")
+	assert.Error(t, check("/users/{user_id}/friends/{user_id}", reflect.TypeFor[structs.Path[UserPath]]()),
+		"route /users/{user_id}/friends/{user_id} has several template variables {user_id}")
+}
+
+type TenantHeader struct {
+	Tenant string `header:"X-Tenant" description:"The tenant"`
+}
+
+type VerboseQuery struct {
+	Verbose bool `query:"verbose" description:"More details" default:"false"`
+}
+
+type TerseQuery struct {
+	Verbose bool `query:"verbose" description:"Fewer details" default:"true"`
+}
+
+// Test that parameters are shared between verbs, explicitly or automatically.
+func TestSharedParameters(t *testing.T) {
+	result, err := path.FromPath(path.Implementation{
+		Path:       "/users/{user_id}",
+		Parameters: reflect.TypeFor[struct{ Header TenantHeader }](),
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			path.Get: {
+				Input: reflect.TypeFor[structs.PathQuery[UserPath, VerboseQuery]](),
+			},
+			path.Delete: {
+				Input: reflect.TypeFor[struct {
+					Path   UserPath
+					Header TenantHeader
+				}](),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, result, `{
+		"parameters": [
+			{
+				"name": "X-Tenant",
+				"in": "header",
+				"description": "The tenant",
+				"required": true,
+				"schema": {"type": "string"}
+			},
+			{
+				"name": "user_id",
+				"in": "path",
+				"description": "The id of the user",
+				"required": true,
+				"schema": {"type": "string"}
+			}
+		],
+		"get": {
+			"summary": "",
+			"operationId": "get /users/{user_id}",
+			"parameters": [
+				{
+					"name": "verbose",
+					"in": "query",
+					"description": "More details",
+					"schema": {"type": "boolean", "default": false}
+				}
+			],
+			"responses": {"default": {"description": ""}}
+		},
+		"delete": {
+			"summary": "",
+			"operationId": "delete /users/{user_id}",
+			"responses": {"default": {"description": ""}}
+		}
+	}`)
+
+	// A single verb keeps its parameters.
+	result, err = path.FromPath(path.Implementation{
+		Path: "/users/{user_id}",
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			path.Get: {
+				Input: reflect.TypeFor[structs.Path[UserPath]](),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, result.Parameters == nil)
+	assert.Equal(t, len(result.Get.Parameters), 1)
+
+	// Parameters defined differently are left on each operation.
+	result, err = path.FromPath(path.Implementation{
+		Path: "/users/{user_id}",
+		PerVerb: map[path.Verb]path.VerbImplementation{
+			path.Get: {
+				Input: reflect.TypeFor[structs.PathQuery[UserPath, VerboseQuery]](),
+			},
+			path.Delete: {
+				Input: reflect.TypeFor[structs.PathQuery[UserPath, TerseQuery]](),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(*result.Parameters), 1)
+	assert.Equal(t, len(result.Get.Parameters), 1)
+	assert.Equal(t, len(result.Delete.Parameters), 1)
+	testutils.EqualJSON(t, result.Delete.Parameters, `[
+		{
+			"name": "verbose",
+			"in": "query",
+			"description": "Fewer details",
+			"schema": {"type": "boolean", "default": true}
+		}
+	]`)
+
+	_, err = path.FromPath(path.Implementation{
+		Path:       "/users",
+		Parameters: reflect.TypeFor[structs.Body[VerboseQuery]](),
+	})
+	assert.ErrorContains(t, err, "it may not have fields other than Path, Query, Header, found Body")
+}
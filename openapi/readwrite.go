@@ -0,0 +1,157 @@
+package openapi
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/pasqal-io/gousset/openapi/media"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/openapi/request"
+	"github.com/pasqal-io/gousset/openapi/response"
+	"github.com/pasqal-io/gousset/openapi/schema"
+	"github.com/pasqal-io/gousset/shared"
+)
+
+// A body of an operation, as declared in the implementation and in the spec.
+type body struct {
+	typ       reflect.Type
+	direction schema.Direction
+	content   map[string]media.Type
+	mediaType string
+}
+
+// The name of the component for a named Go type, e.g. `UserInput`.
+func directedName(typ reflect.Type, direction schema.Direction) (string, error) {
+	name, err := schema.ComponentName(typ)
+	if err != nil {
+		return "", err
+	}
+	switch direction {
+	case schema.DirectionRequest:
+		return name + "Input", nil
+	default:
+		return name + "Output", nil
+	}
+}
+
+// Strip pointers, which do not affect the schema of a body.
+func underlying(typ reflect.Type) reflect.Type {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// Collect the request and response bodies of the endpoints.
+//
+// Maps are traversed in sorted order, so that errors are reproducible.
+func collectBodies(implem Implementation, spec Spec) ([]body, error) {
+	var result []body
+	addResponse := func(impl response.ResponseImplementation, resp response.Response) {
+		respSpec, ok := resp.(response.Spec)
+		if !ok || impl.Content == nil || respSpec.Content == nil {
+			return
+		}
+		for _, mediaType := range sortedKeys(*impl.Content) {
+			result = append(result, body{
+				typ:       underlying((*impl.Content)[mediaType].Type),
+				direction: schema.DirectionResponse,
+				content:   *respSpec.Content,
+				mediaType: mediaType,
+			})
+		}
+	}
+	for _, pathImpl := range implem.Endpoints {
+		route, _, err := path.ParseRoute(pathImpl.Path, implem.Routes)
+		if err != nil {
+			return nil, err
+		}
+		operations := spec.Paths[route].Operations()
+		for _, verb := range sortedKeys(pathImpl.PerVerb) {
+			verbImpl := pathImpl.PerVerb[verb]
+			op, ok := operations[verb]
+			if !ok {
+				continue
+			}
+			if verbImpl.Input != nil && op.Request != nil {
+				if field, ok := verbImpl.Input.FieldByName("Body"); ok {
+					if reqSpec, ok := (*op.Request).(request.Spec); ok {
+						for _, mediaType := range sortedKeys(reqSpec.Content) {
+							result = append(result, body{
+								typ:       underlying(field.Type),
+								direction: schema.DirectionRequest,
+								content:   reqSpec.Content,
+								mediaType: mediaType,
+							})
+						}
+					}
+				}
+			}
+			addResponse(verbImpl.Response.Default, op.Responses.Default)
+			if verbImpl.Response.PerCode != nil && op.Responses.PerCode != nil {
+				for _, code := range slices.Sorted(maps.Keys(*verbImpl.Response.PerCode)) {
+					addResponse((*verbImpl.Response.PerCode)[code], (*op.Responses.PerCode)[code])
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// Replace the bodies of named types that are used both in requests and in
+// responses and contain `readOnly` or `writeOnly` properties with references
+// to distinct `<Name>Input` and `<Name>Output` components.
+func splitReadWrite(implem Implementation, spec *Spec) error {
+	bodies, err := collectBodies(implem, *spec)
+	if err != nil {
+		return err
+	}
+
+	// Determine which types need to be split.
+	directions := make(map[reflect.Type]map[schema.Direction]bool)
+	for _, b := range bodies {
+		if b.typ == nil || b.typ.Name() == "" {
+			continue
+		}
+		mediaType := b.content[b.mediaType]
+		if mediaType.Schema == nil || !schema.HasReadWriteOnly(*mediaType.Schema) {
+			continue
+		}
+		if directions[b.typ] == nil {
+			directions[b.typ] = make(map[schema.Direction]bool)
+		}
+		directions[b.typ][b.direction] = true
+	}
+
+	// Move the split types to components.
+	//
+	// The type documented by each component, to detect collisions, e.g. types
+	// with the same name in distinct packages.
+	owners := make(map[string]reflect.Type)
+	for _, b := range bodies {
+		if len(directions[b.typ]) != 2 {
+			continue
+		}
+		mediaType := b.content[b.mediaType]
+		name, err := directedName(b.typ, b.direction)
+		if err != nil {
+			return err
+		}
+		if other, ok := owners[name]; ok && other != b.typ {
+			return fmt.Errorf("cannot split schemas of %s and %s, as they both map to component %s", other.String(), b.typ.String(), name)
+		}
+		owners[name] = b.typ
+		if spec.Components.Schemas == nil {
+			spec.Components.Schemas = shared.Ptr(make(map[string]schema.Schema))
+		}
+		schemas := *spec.Components.Schemas
+		if _, ok := schemas[name]; !ok {
+			schemas[name] = schema.Project(*mediaType.Schema, b.direction)
+		}
+		mediaType.Schema = shared.Ptr[schema.Schema](schema.Ref("#/components/schemas/" + name))
+		b.content[b.mediaType] = mediaType
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/media"
 	"github.com/pasqal-io/gousset/openapi/schema"
@@ -37,12 +38,29 @@ func (Reference) sealed() {}
 var _ Request = Reference{}
 
 func FromField(from reflect.StructField) (Request, error) {
+	return FromImplementation(Implementation{Field: from})
+}
+
+// User-provided metadata on the body of a request, i.e. field `Body` of an input.
+type Implementation struct {
+	Field reflect.StructField
+
+	// Where to report diagnostics. If nil, they are logged.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	Comments doc.Comments `exhaustruct:"optional"`
+}
+
+// As `FromField`, with the options of `impl`.
+func FromImplementation(impl Implementation) (Request, error) {
+	from, report := impl.Field, impl.Diagnostics
 	// Extract summary and description.
-	description := doc.GetDescription(from.Type)
+	description := doc.GetDescriptionOrComment(impl.Comments, from.Type)
 	content := make(map[string]media.Type)
-	schema, err := schema.FromImplementation(schema.Implementation{Type: from.Type, PublicNameKey: "json"})
+	schema, err := schema.FromImplementation(schema.Implementation{Type: from.Type, PublicNameKey: "json", Diagnostics: report, Comments: impl.Comments})
 	if err != nil {
-		return Spec{}, fmt.Errorf("failed to extract the type of body %s: %w", from.Type.String(), err)
+		return Spec{}, diagnostic.Wrap(err, fmt.Sprintf("failed to extract the type of body %s", from.Type.String()))
 	}
 
 	// FIXME: Support example.
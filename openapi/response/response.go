@@ -3,8 +3,12 @@ package response
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/pasqal-io/gousset/inner/serialization"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
+	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/header"
 	"github.com/pasqal-io/gousset/openapi/link"
 	"github.com/pasqal-io/gousset/openapi/media"
@@ -27,6 +31,10 @@ func (Reference) sealed() {}
 var _ Response = Ref("")
 
 type Responses struct {
+	// The response for status codes not listed in PerCode.
+	//
+	// If nil, the operation does not declare a default response. It is
+	// still serialized as a response with an empty description.
 	Default Response             `json:"default"`
 	PerCode *map[uint16]Response `json:"-,omitempty" flatten:""`
 }
@@ -34,7 +42,7 @@ type Responses struct {
 func (r Responses) MarshalJSON() ([]byte, error) {
 	var nilResponse Response = nil
 	if r.Default == nilResponse {
-		return []byte{}, fmt.Errorf("error while flattening Responses for serialization: Default cannot be nil")
+		r.Default = Spec{}
 	}
 	flattened, err := serialization.FlattenStructToJSON(r)
 	if err != nil {
@@ -61,28 +69,53 @@ var _ Response = Spec{}
 type Implementation struct {
 	Default ResponseImplementation
 	PerCode *map[uint16]ResponseImplementation
+
+	// Where to report diagnostics. If nil, they are logged.
+	//
+	// Inherited by each response unless it specifies its own.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	//
+	// Inherited by each response unless it specifies its own.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
+// Compile the responses of an operation.
+//
+// Errors are collected across responses.
 func FromImplementation(impl Implementation) (Responses, error) {
-	def, err := FromResponseImplementation(impl.Default)
-	if err != nil {
-		return Responses{}, fmt.Errorf("while compiling response, error in default response: %w", err)
+	var errs []error
+	inherit := func(response ResponseImplementation) ResponseImplementation {
+		if response.Diagnostics == nil {
+			response.Diagnostics = impl.Diagnostics
+		}
+		if response.Comments == nil {
+			response.Comments = impl.Comments
+		}
+		return response
 	}
-	result := Responses{
-		Default: def,
+	result := Responses{}
+	if impl.Default.isDeclared() {
+		def, err := FromResponseImplementation(inherit(impl.Default))
+		if err != nil {
+			errs = append(errs, diagnostic.Wrap(err, "while compiling response, error in default response"))
+		}
+		result.Default = def
 	}
 	if impl.PerCode != nil {
 		perCode := make(map[uint16]Response)
-		for k, v := range *impl.PerCode {
-			response, err := FromResponseImplementation(v)
+		for _, k := range slices.Sorted(maps.Keys(*impl.PerCode)) {
+			response, err := FromResponseImplementation(inherit((*impl.PerCode)[k]))
 			if err != nil {
-				return Responses{}, fmt.Errorf("while compiling response, error in response code %d: %w", k, err)
+				errs = append(errs, diagnostic.Wrap(err, fmt.Sprintf("while compiling response, error in response code %d", k)))
+				continue
 			}
 			perCode[k] = response
 		}
 		result.PerCode = &perCode
 	}
-	return result, nil
+	return result, diagnostic.Join(errs...)
 }
 
 type ResponseImplementation struct {
@@ -90,41 +123,78 @@ type ResponseImplementation struct {
 	Headers     *map[string]header.Implementation
 	Content     *map[string]media.Implementation
 	Links       *map[string]link.Implementation
+
+	// Where to report diagnostics. If nil, they are logged.
+	//
+	// Inherited by headers and media types unless they specify their own.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+
+	// Doc comments, see `schema.Implementation.Comments`.
+	//
+	// Inherited by headers and media types unless they specify their own.
+	Comments doc.Comments `exhaustruct:"optional"`
+}
+
+// Return `true` unless the response was left to its zero value.
+func (impl ResponseImplementation) isDeclared() bool {
+	return impl.Description != "" || impl.Headers != nil || impl.Content != nil || impl.Links != nil
 }
 
 func FromResponseImplementation(impl ResponseImplementation) (Response, error) {
 	result := Spec{
 		Description: impl.Description,
 	}
+	var errs []error
 	if impl.Headers != nil {
 		result.Headers = shared.Ptr(make(map[string]header.Header))
-		for k, v := range *impl.Headers {
+		// Sorted, to make errors reproducible.
+		for _, k := range slices.Sorted(maps.Keys(*impl.Headers)) {
+			v := (*impl.Headers)[k]
+			if v.Diagnostics == nil {
+				v.Diagnostics = impl.Diagnostics
+			}
+			if v.Comments == nil {
+				v.Comments = impl.Comments
+			}
 			h, err := header.FromImplementation(v)
 			if err != nil {
-				return result, fmt.Errorf("while compiling header %s, error: %w", k, err)
+				errs = append(errs, diagnostic.Wrap(err, fmt.Sprintf("while compiling header %s, error", k)))
+				continue
 			}
 			(*result.Headers)[k] = h
 		}
 	}
 	if impl.Content != nil {
 		result.Content = shared.Ptr(make(map[string]media.Type))
-		for k, v := range *impl.Content {
+		// Sorted, to make errors reproducible.
+		for _, k := range slices.Sorted(maps.Keys(*impl.Content)) {
+			v := (*impl.Content)[k]
+			if v.Diagnostics == nil {
+				v.Diagnostics = impl.Diagnostics
+			}
+			if v.Comments == nil {
+				v.Comments = impl.Comments
+			}
 			h, err := media.FromImplementation(v)
 			if err != nil {
-				return result, fmt.Errorf("while compiling media type %s, error: %w", k, err)
+				errs = append(errs, diagnostic.Wrap(err, fmt.Sprintf("while compiling media type %s, error", k)))
+				continue
 			}
 			(*result.Content)[k] = h
 		}
 	}
 	if impl.Links != nil {
 		result.Links = shared.Ptr(make(map[string]link.Link))
-		for k, v := range *impl.Links {
+		// Sorted, to make errors reproducible.
+		for _, k := range slices.Sorted(maps.Keys(*impl.Links)) {
+			v := (*impl.Links)[k]
 			h, err := link.FromImplementation(v)
 			if err != nil {
-				return result, fmt.Errorf("while compiling link %s, error: %w", k, err)
+				errs = append(errs, diagnostic.Wrap(err, fmt.Sprintf("while compiling link %s, error", k)))
+				continue
 			}
 			(*result.Links)[k] = h
 		}
 	}
-	return result, nil
+	return result, diagnostic.Join(errs...)
 }
@@ -0,0 +1,30 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	// The package qualifier of a type argument, e.g. `github.com/example/models.`.
+	qualifierRegex = regexp.MustCompile(`[^\[\],]*\.`)
+
+	// Characters that may not appear in the name of a component.
+	invalidComponentRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// The name under which a named type is documented in `#/components/schemas/`,
+// e.g. `User` or, for a generic type `Page[models.User]`, `Page_User`.
+//
+// Package paths are dropped, so types of distinct packages may have the
+// same name. Callers must detect collisions.
+func ComponentName(typ reflect.Type) (string, error) {
+	name := typ.Name()
+	if name == "" {
+		return "", fmt.Errorf("type %s has no name, it cannot be documented as a component", typ.String())
+	}
+	name = qualifierRegex.ReplaceAllString(name, "")
+	return strings.Trim(invalidComponentRegex.ReplaceAllString(name, "_"), "_"), nil
+}
@@ -0,0 +1,115 @@
+package schema
+
+import "slices"
+
+// The direction in which a value is sent.
+type Direction int
+
+const (
+	// The value is sent by the client, e.g. a request body.
+	//
+	// Properties marked as `readOnly` are not part of requests.
+	DirectionRequest Direction = iota
+
+	// The value is sent by the server, e.g. a response body.
+	//
+	// Properties marked as `writeOnly` are not part of responses.
+	DirectionResponse
+)
+
+// Return `true` if the schema or any of its children has a property
+// marked as `readOnly` or `writeOnly`.
+//
+// References are not followed.
+func HasReadWriteOnly(s Schema) bool {
+	switch typed := s.(type) {
+	case Object:
+		for _, property := range typed.Properties {
+			if excluded(property, DirectionRequest) || excluded(property, DirectionResponse) || HasReadWriteOnly(property) {
+				return true
+			}
+		}
+		return typed.AdditionalProperties != nil && HasReadWriteOnly(*typed.AdditionalProperties)
+	case Array:
+		return HasReadWriteOnly(typed.Items)
+	case OneOf:
+		return slices.ContainsFunc(typed.OneOf, HasReadWriteOnly)
+	case AllOf:
+		return slices.ContainsFunc(typed.AllOf, HasReadWriteOnly)
+	default:
+		return false
+	}
+}
+
+// Return `true` if a property should be removed from values sent in a direction.
+func excluded(s Schema, direction Direction) bool {
+	var share Shared
+	switch typed := s.(type) {
+	case Primitive:
+		share = typed.Shared
+	case Object:
+		share = typed.Shared
+	case Array:
+		share = typed.Shared
+	default:
+		return false
+	}
+	switch direction {
+	case DirectionRequest:
+		return share.ReadOnly
+	case DirectionResponse:
+		return share.WriteOnly
+	default:
+		return false
+	}
+}
+
+// Return a copy of a schema restricted to the values sent in a direction,
+// i.e. without the properties that are `readOnly` (for requests) or
+// `writeOnly` (for responses), which are also removed from `required`.
+//
+// References are not followed.
+func Project(s Schema, direction Direction) Schema {
+	switch typed := s.(type) {
+	case Object:
+		result := typed
+		result.Required = nil
+		if typed.Properties != nil {
+			result.Properties = make(map[string]Schema, len(typed.Properties))
+			for name, property := range typed.Properties {
+				if excluded(property, direction) {
+					continue
+				}
+				result.Properties[name] = Project(property, direction)
+			}
+		}
+		for _, name := range typed.Required {
+			if _, ok := result.Properties[name]; ok {
+				result.Required = append(result.Required, name)
+			}
+		}
+		if typed.AdditionalProperties != nil {
+			additional := Project(*typed.AdditionalProperties, direction)
+			result.AdditionalProperties = &additional
+		}
+		return result
+	case Array:
+		result := typed
+		result.Items = Project(typed.Items, direction)
+		return result
+	case OneOf:
+		result := OneOf{OneOf: make([]Schema, 0, len(typed.OneOf))}
+		for _, variant := range typed.OneOf {
+			result.OneOf = append(result.OneOf, Project(variant, direction))
+		}
+		return result
+	case AllOf:
+		result := AllOf{AllOf: make([]Schema, 0, len(typed.AllOf))}
+		for _, variant := range typed.AllOf {
+			result.AllOf = append(result.AllOf, Project(variant, direction))
+		}
+		return result
+	default:
+		return s
+	}
+}
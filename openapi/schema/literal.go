@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pasqal-io/gousset/shared"
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+)
+
+// Parse the literal value of a tag (e.g. `example` or `default`) according
+// to the Go type of the field it decorates.
+//
+//   - strings are kept as is;
+//   - booleans and numbers are parsed with `strconv`, checking that they fit
+//     in the type, e.g. `300` does not fit in an `int8`;
+//   - `time.Time` must be an RFC 3339 date-time;
+//   - types that implement `encoding.TextUnmarshaler` or `json.Unmarshaler`,
+//     e.g. `net.IP`, are first decoded from the literal as a JSON string;
+//   - `[]byte` must be base64, as with `encoding/json`;
+//   - other slices, arrays, maps and structs must be JSON that decodes into the type.
+//
+// Returns the value as it should appear in the JSON spec.
+func ParseLiteral(typ reflect.Type, literal string) (shared.Json, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		if _, err := time.Parse(time.RFC3339Nano, literal); err != nil {
+			return nil, fmt.Errorf("expected an RFC 3339 date-time for %s, got \"%s\"", typ.String(), literal)
+		}
+		return literal, nil
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return literal, nil
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(literal)
+		if err != nil {
+			return nil, fmt.Errorf("expected a boolean for %s, got \"%s\"", typ.String(), literal)
+		}
+		return parsed, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(literal, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer fitting in %s, got \"%s\"", typ.String(), literal)
+		}
+		return parsed, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(literal, 10, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected a non-negative integer fitting in %s, got \"%s\"", typ.String(), literal)
+		}
+		return parsed, nil
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(literal, typ.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected a number fitting in %s, got \"%s\"", typ.String(), literal)
+		}
+		return parsed, nil
+	case reflect.Interface:
+		// Accept any JSON, falling back to a string.
+		var parsed any
+		if err := json.Unmarshal([]byte(literal), &parsed); err != nil {
+			return literal, nil
+		}
+		return parsed, nil
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		ptr := reflect.PointerTo(typ)
+		if ptr.Implements(textUnmarshalerType) || ptr.Implements(jsonUnmarshalerType) {
+			// Types such as `net.IP` are generally serialized as strings.
+			quoted, err := json.Marshal(literal)
+			if err != nil {
+				return nil, fmt.Errorf("while quoting literal \"%s\": %w", literal, err)
+			}
+			if err := json.Unmarshal(quoted, reflect.New(typ).Interface()); err == nil {
+				return literal, nil
+			}
+		} else if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			if _, err := base64.StdEncoding.DecodeString(literal); err != nil {
+				return nil, fmt.Errorf("expected base64 for %s, got \"%s\"", typ.String(), literal)
+			}
+			return literal, nil
+		}
+		// Check that the literal fits in the type...
+		decoder := json.NewDecoder(bytes.NewReader([]byte(literal)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(reflect.New(typ).Interface()); err != nil {
+			return nil, fmt.Errorf("expected JSON matching %s, got \"%s\": %w", typ.String(), literal, err)
+		}
+		// ...but keep it as written, e.g. without adding zero values for missing fields.
+		var parsed any
+		if err := json.Unmarshal([]byte(literal), &parsed); err != nil {
+			return nil, fmt.Errorf("expected JSON matching %s, got \"%s\": %w", typ.String(), literal, err)
+		}
+		return parsed, nil
+	default:
+		return nil, fmt.Errorf("cannot parse a literal for type %s", typ.String())
+	}
+}
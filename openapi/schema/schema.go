@@ -9,13 +9,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log/slog"
 	"maps"
 	"reflect"
 	"slices"
 	"time"
 
 	"github.com/pasqal-io/gousset/inner/tags"
+	"github.com/pasqal-io/gousset/openapi/diagnostic"
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
 	"github.com/pasqal-io/gousset/shared"
@@ -40,12 +40,27 @@ type Shared struct {
 	// An optional example.
 	Example *shared.Json `json:"example,omitempty"`
 
+	// The value assumed by the server if none is provided.
+	Default *shared.Json `json:"default,omitempty"`
+
 	// The JavaScript type for this schema.
 	Type Type `json:"type"`
 
 	// A well-known format, e.g. "email".
 	Format *string `json:"format,omitempty"`
 
+	// A description of the value. May include Markdown.
+	Description *string `json:"description,omitempty"`
+
+	// If true, the value is deprecated and should be avoided.
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	// If true, the value is only sent by the server, e.g. an id generated by the server.
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// If true, the value is only sent by the client, e.g. a password.
+	WriteOnly bool `json:"writeOnly,omitempty"`
+
 	Title            *string  `json:"title,omitempty"`
 	MultipleOf       *float64 `json:"multipleOf,omitempty"`
 	Maximum          *float64 `json:"maximum,omitempty"`
@@ -60,6 +75,12 @@ type Shared struct {
 	MaxProperties    *int64   `json:"maxProperties,omitempty"`
 	MinProperties    *int64   `json:"minProperties,omitempty"`
 	Enum             *[]any   `json:"enum,omitempty"`
+
+	// The names of the constants for the values of `Enum`, for the benefit of code generators.
+	EnumVarNames *[]string `json:"x-enum-varnames,omitempty"`
+
+	// The descriptions of the values of `Enum`, in the same order.
+	EnumDescriptions *[]string `json:"x-enum-descriptions,omitempty"`
 }
 
 type Type string
@@ -122,9 +143,40 @@ type AllOf struct {
 
 func (AllOf) sealed() {}
 
+// A reference to a schema defined elsewhere, typically in
+// `#/components/schemas/`.
+type Reference shared.Reference
+
+func Ref(to string) Reference {
+	return Reference(shared.Ref(to))
+}
+
+func (Reference) sealed() {}
+
+var _ Schema = Reference{}
+
+// A schema that accepts any value (`true`) or no value (`false`).
+//
+// Use `False` as `AdditionalProperties` to forbid properties that are
+// not listed in `Properties`.
+type Boolean bool
+
+const (
+	True  = Boolean(true)
+	False = Boolean(false)
+)
+
+func (Boolean) sealed() {}
+
+var _ Schema = True
+
 type Implementation struct {
 	Type             reflect.Type
 	PublicNameKey    string
+	Description      *string
+	Deprecated       bool
+	ReadOnly         bool
+	WriteOnly        bool
 	Title            *string
 	MultipleOf       *float64
 	Maximum          *float64
@@ -140,7 +192,16 @@ type Implementation struct {
 	MinProperties    *int64
 	Enum             *[]any
 	Format           *string
-	Example          *string
+	// An example, typically parsed from tag `example` with `ParseLiteral`.
+	Example *shared.Json
+	// A default value, typically parsed from tag `default` with `ParseLiteral`.
+	Default *shared.Json
+	// Where to report diagnostics. If nil, they are logged.
+	Diagnostics *diagnostic.Report `exhaustruct:"optional"`
+	// If provided, doc comments serve as descriptions of types and fields that
+	// have neither a tag `description` nor a method `Description()`, and the
+	// constants of named types serve as their enum, see package `extract`.
+	Comments doc.Comments `exhaustruct:"optional"`
 }
 
 var stringType = reflect.TypeOf("")
@@ -162,6 +223,10 @@ func fill[T any, I any](field **T, value any, cb func(I) T) {
 func fromImplementationSingleVariant(impl Implementation, restriction map[string]bool) (Schema, error) {
 	errorReturn := AllOf{}
 	share := Shared{
+		Description:      impl.Description,
+		Deprecated:       impl.Deprecated,
+		ReadOnly:         impl.ReadOnly,
+		WriteOnly:        impl.WriteOnly,
 		Title:            impl.Title,
 		MultipleOf:       impl.MultipleOf,
 		Maximum:          impl.Maximum,
@@ -177,6 +242,9 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 		MinProperties:    impl.MinProperties,
 		Enum:             impl.Enum,
 		Format:           impl.Format,
+		// If provided, these take precedence over HasExample and HasDefault.
+		Example: impl.Example,
+		Default: impl.Default,
 	}
 	phony := reflect.New(impl.Type)
 	if phony.CanInterface() {
@@ -185,10 +253,18 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 		if hasSchema, ok := asAny.(HasSchema); ok {
 			return hasSchema.Schema(), nil
 		}
+		if share.Description == nil {
+			// Either from HasDescription or from the doc comment of the type.
+			share.Description = doc.GetDescriptionOrComment(impl.Comments, impl.Type)
+		}
 		fill(&share.ExternalDocs, asAny, func(value doc.HasExternalDocs) doc.External { return value.Docs() })
 		fill(&share.Example, asAny, func(value example.HasExample) shared.Json { return value.Example() })
+		fill(&share.Default, asAny, func(value HasDefault) shared.Json { return value.Default() })
 		fill(&share.Format, asAny, func(value HasFormat) string { return string(value.Format()) })
 		fill(&share.Enum, asAny, func(value IsEnum) []shared.Json { return value.Enum() })
+		if share.Enum == nil {
+			fillEnumFromConstants(&share, impl.Comments, impl.Type)
+		}
 		fill(&share.MinItems, asAny, func(value HasMinArrayLength) int64 { return value.MinArrayLength() })
 		fill(&share.MaxItems, asAny, func(value HasMaxArrayLength) int64 { return value.MaxArrayLength() })
 		fill(&share.MinLength, asAny, func(value HasMinStringLength) int64 { return value.MinStringLength() })
@@ -209,11 +285,6 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 			}, nil
 		}
 	}
-	if impl.Example != nil {
-		var example any = *impl.Example
-		share.Example = &example
-	}
-
 	switch impl.Type.Kind() {
 	case reflect.Interface:
 	case reflect.Pointer:
@@ -263,9 +334,17 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 	case reflect.Slice:
 		subImpl := impl
 		subImpl.Type = impl.Type.Elem()
+		// The documentation, example and default describe the array, not its items.
+		subImpl.Description = nil
+		subImpl.Deprecated = false
+		subImpl.ReadOnly = false
+		subImpl.WriteOnly = false
+		subImpl.Example = nil
+		subImpl.Default = nil
 		items, err := FromImplementation(subImpl)
 		if err != nil {
-			return errorReturn, fmt.Errorf("while compiling schema for %s, failed to extract type from the elements of array/slice", impl.Type.String())
+			// Not wrapped, to keep the errors of the elements flat.
+			return errorReturn, err
 		}
 		share.Type = TypeArray
 		return Array{
@@ -278,6 +357,8 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 		properties := make(map[string]Schema)
 		patternProperties := make(map[string]Schema)
 		var fields []reflect.StructField
+		// Errors are collected across fields, to report them all at once.
+		var errs []error
 		for i := 0; i < impl.Type.NumField(); i++ {
 			field := impl.Type.Field(i)
 			if restriction != nil {
@@ -294,9 +375,11 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 				if !field.IsExported() {
 					continue
 				}
+				at := diagnostic.Error{Type: impl.Type.String(), Field: field.Name}
 				tags, err := tags.Parse(field.Tag)
 				if err != nil {
-					return errorReturn, fmt.Errorf("while compiling schema for struct %s, failed to parse tags for field %s", impl.Type.String(), field.Name)
+					errs = append(errs, diagnostic.Locate(fmt.Errorf("while compiling schema for struct %s, failed to parse tags for field %s: %w", impl.Type.String(), field.Name, err), at))
+					continue
 				}
 
 				if tags.IsFlattened() || field.Anonymous {
@@ -319,14 +402,15 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 						case reflect.String:
 							scheme, err := FromImplementation(subImpl)
 							if err != nil {
-								return errorReturn, fmt.Errorf("while compiling schema for struct %s, cannot extract scheme for contents of map at field %s: %w", impl.Type.String(), field.Name, err)
+								errs = append(errs, diagnostic.Locate(err, at))
+								continue
 							}
 							patternProperties["*"] = scheme
 						default:
-							return errorReturn, fmt.Errorf("while compiling schema for %s, this type of map is not implemented at field %s", impl.Type.String(), field.Name)
+							errs = append(errs, diagnostic.Locate(fmt.Errorf("while compiling schema for %s, this type of map is not implemented at field %s", impl.Type.String(), field.Name), at))
 						}
 					default:
-						return errorReturn, fmt.Errorf("while compiling schema for %s, field %s is marked as flattened but is not a struct, got %s", impl.Type.String(), field.Name, field.Type.String())
+						errs = append(errs, diagnostic.Locate(fmt.Errorf("while compiling schema for %s, field %s is marked as flattened but is not a struct, got %s", impl.Type.String(), field.Name, field.Type.String()), at))
 					}
 				} else {
 					// Treat field as an object.
@@ -334,17 +418,22 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 					if publicName := tags.PublicFieldName(impl.PublicNameKey); publicName != nil {
 						name = *publicName
 					} else {
-						return errorReturn, fmt.Errorf("while compiling schema for struct %s, field %s doesn't have a public name, expecting a tag `%s`", impl.Type.String(), field.Name, impl.PublicNameKey)
+						errs = append(errs, diagnostic.Locate(fmt.Errorf("while compiling schema for struct %s, field %s doesn't have a public name, expecting a tag `%s`", impl.Type.String(), field.Name, impl.PublicNameKey), at))
+						continue
 					}
 
-					subImpl, err := ImplementationFromStructField(field, impl.PublicNameKey)
+					subImpl, err := ImplementationFromFieldOf(impl.Type, field, impl.PublicNameKey, impl.Comments)
 					if err != nil {
-						return errorReturn, fmt.Errorf("while compiling a schema for struct %s, error in field %s: %w", impl.Type.String(), field.Name, err)
+						errs = append(errs, diagnostic.Locate(fmt.Errorf("while compiling a schema for struct %s, error in field %s: %w", impl.Type.String(), field.Name, err), at))
+						continue
 					}
+					subImpl.Diagnostics = impl.Diagnostics
 
 					fieldSchema, err := FromImplementation(subImpl)
 					if err != nil {
-						return errorReturn, fmt.Errorf("while compiling schema for %s, failed to extract scheme from field %s: %w", impl.Type.String(), field.Name, err)
+						// Errors within the field are more precisely located than this field.
+						errs = append(errs, diagnostic.Locate(err, at))
+						continue
 					}
 
 					if tags.Default() == nil && !tags.IsPreinitialized() && tags.MethodName() == nil {
@@ -354,6 +443,9 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 				}
 			}
 		}
+		if len(errs) != 0 {
+			return errorReturn, diagnostic.Join(errs...)
+		}
 		share.Type = TypeObject
 		return Object{
 			Shared:     share,
@@ -367,10 +459,13 @@ func fromImplementationSingleVariant(impl Implementation, restriction map[string
 		subImpl := Implementation{
 			Type:          impl.Type.Elem(),
 			PublicNameKey: impl.PublicNameKey,
+			Diagnostics:   impl.Diagnostics,
+			Comments:      impl.Comments,
 		}
 		contentSchema, err := FromImplementation(subImpl)
 		if err != nil {
-			return errorReturn, fmt.Errorf("while compiling schema for map %s, failed to extract scheme from content: %w", impl.Type.String(), err)
+			// Not wrapped, to keep the errors of the values flat.
+			return errorReturn, err
 		}
 		share.Type = TypeObject
 		return Object{
@@ -433,9 +528,12 @@ func FromImplementation(impl Implementation) (Schema, error) {
 			key = k
 			break
 		}
-		slog.Warn("gousset.openapi.schema.FromImplementation: encountering a struct with a single variant, tag `variants` seems misapplied",
-			"type", impl.Type.String(),
-			"variant", key)
+		impl.Diagnostics.Add(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     diagnostic.CodeMisappliedVariant,
+			Message:  fmt.Sprintf("struct has a single variant %s, tag `variant` seems misapplied", key),
+			Type:     impl.Type.String(),
+		})
 		return fromImplementationSingleVariant(impl, nil)
 	default:
 		// Alright, this is a true sum type, let's build it.
@@ -466,7 +564,19 @@ func FromImplementation(impl Implementation) (Schema, error) {
 	}
 }
 
+// Prepare the schema of a field.
+//
+// The description of the field is taken from its tag `description`. Use
+// ImplementationFromFieldOf to fall back to its doc comment.
 func ImplementationFromStructField(field reflect.StructField, publicNameKey string) (Implementation, error) {
+	return ImplementationFromFieldOf(nil, field, publicNameKey, nil)
+}
+
+// Prepare the schema of a field of struct `container`.
+//
+// The description of the field is taken from its tag `description` or, failing
+// that, from its doc comment in `comments`, unless `container` is nil.
+func ImplementationFromFieldOf(container reflect.Type, field reflect.StructField, publicNameKey string, comments doc.Comments) (Implementation, error) {
 	tags, err := tags.Parse(field.Tag)
 	if err != nil {
 		return Implementation{}, fmt.Errorf("failed to parse tags for field %s: %w", field.Name, err)
@@ -475,19 +585,58 @@ func ImplementationFromStructField(field reflect.StructField, publicNameKey stri
 	result := Implementation{
 		Type:          field.Type,
 		PublicNameKey: publicNameKey,
+		Comments:      comments,
 	}
 	type Pair[T any, U any] struct {
 		First  T
 		Second U
 	}
 	for _, parse := range []Pair[**string, string]{
+		{&result.Description, "description"},
 		{&result.Title, "title"},
 		{&result.Format, "format"},
 		{&result.Pattern, "pattern"},
-		{&result.Example, "example"},
 	} {
 		*parse.First = tags.LookupString(parse.Second)
 	}
+	for _, parse := range []Pair[*bool, string]{
+		{&result.Deprecated, "deprecated"},
+		{&result.ReadOnly, "readOnly"},
+		{&result.WriteOnly, "writeOnly"},
+	} {
+		*parse.First, err = tags.LookupFlag(parse.Second)
+		if err != nil {
+			return Implementation{}, fmt.Errorf("while compiling schema for field %s, invalid tag %s: %w", field.Name, parse.Second, err)
+		}
+	}
+	if result.Description == nil && container != nil {
+		result.Description = doc.GetFieldDescription(comments, container, field)
+	}
+	if result.ReadOnly && result.WriteOnly {
+		return Implementation{}, fmt.Errorf("while compiling schema for field %s, tags readOnly and writeOnly are mutually exclusive", field.Name)
+	}
+	if tags.MethodName() != nil {
+		// The default value is computed at runtime, so we cannot document it as a `default`.
+		description := result.Description
+		if description == nil {
+			description = doc.GetDescriptionOrComment(comments, field.Type)
+		}
+		result.Description = doc.WithMethodNote(description)
+	}
+	if literal := tags.Example(); literal != nil {
+		example, err := ParseLiteral(field.Type, *literal)
+		if err != nil {
+			return Implementation{}, fmt.Errorf("while compiling schema for field %s, invalid tag example: %w", field.Name, err)
+		}
+		result.Example = &example
+	}
+	if literal := tags.Default(); literal != nil {
+		def, err := ParseLiteral(field.Type, *literal)
+		if err != nil {
+			return Implementation{}, fmt.Errorf("while compiling schema for field %s, invalid tag default: %w", field.Name, err)
+		}
+		result.Default = &def
+	}
 	for _, parse := range []Pair[**float64, string]{
 		{&result.ExclusiveMaximum, "exclusiveMaximum"},
 		{&result.ExclusiveMinimum, "exclusiveMinimum"},
@@ -524,12 +673,50 @@ func ImplementationFromStructField(field reflect.StructField, publicNameKey stri
 
 // Implement this on a type to specify that it should be marked as an enum.
 //
-// For more sophisticated cases, see `IsOneOf`.
+// For more sophisticated cases, see `IsOneOf`. To use the constants
+// declared for the type instead, see `Implementation.Comments`.
 type IsEnum interface {
 	// The list of possibilities for this enum.
 	Enum() []shared.Json
 }
 
+// Use the constants declared for a named type as its enum, if any.
+//
+// This requires doc comments, see `Implementation.Comments`.
+func fillEnumFromConstants(share *Shared, comments doc.Comments, typ reflect.Type) {
+	constants := doc.GetConstants(comments, typ)
+	if len(constants) == 0 {
+		return
+	}
+	values := make([]any, 0, len(constants))
+	names := make([]string, 0, len(constants))
+	descriptions := make([]string, 0, len(constants))
+	hasDescriptions := false
+	for _, constant := range constants {
+		values = append(values, constant.Value)
+		names = append(names, constant.Name)
+		if constant.Comment != nil {
+			descriptions = append(descriptions, *constant.Comment)
+			hasDescriptions = true
+		} else {
+			descriptions = append(descriptions, "")
+		}
+	}
+	share.Enum = &values
+	share.EnumVarNames = &names
+	if hasDescriptions {
+		share.EnumDescriptions = &descriptions
+	}
+}
+
+// Implement this on a type to specify the value assumed if none is provided.
+//
+// A tag `default` on a field takes precedence.
+type HasDefault interface {
+	// The default value.
+	Default() shared.Json
+}
+
 // Implement this on a string or number to specify that it should match a given format.
 type HasFormat interface {
 	// The format restriction.
@@ -681,6 +868,30 @@ func EqualSchema(left Schema, right Schema) error {
 			return EqualArray(*castLeft, *castRight)
 		}
 	}
+	{
+		castLeft, castRight, ok, err := castBoth[Reference](left, right)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if castLeft.Ref != castRight.Ref {
+				return fmt.Errorf("distinct references: %s != %s", castLeft.Ref, castRight.Ref)
+			}
+			return nil
+		}
+	}
+	{
+		castLeft, castRight, ok, err := castBoth[Boolean](left, right)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if *castLeft != *castRight {
+				return fmt.Errorf("distinct boolean schemas: %v != %v", *castLeft, *castRight)
+			}
+			return nil
+		}
+	}
 	panic(fmt.Errorf("equality for this type is not implemented yet: %s", reflect.TypeOf(left).String()))
 }
 
@@ -1,14 +1,21 @@
 package schema_test
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pasqal-io/gousset/openapi/doc"
 	"github.com/pasqal-io/gousset/openapi/example"
 	"github.com/pasqal-io/gousset/openapi/schema"
 	"github.com/pasqal-io/gousset/shared"
 	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
 )
 
 // Check the schema for a simple boolean.
@@ -208,3 +215,190 @@ func TestComplexStruc(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// Check that examples are parsed according to the type of the field.
+func TestTypedExamples(t *testing.T) {
+	type Inner struct {
+		Name string `json:"name"`
+	}
+	type WithExamples struct {
+		Age      int            `json:"age" example:"42"`
+		Ratio    float32        `json:"ratio" example:"0.5"`
+		Active   bool           `json:"active" example:"true"`
+		Name     string         `json:"name" example:"John, Jr."`
+		Tags     []string       `json:"tags" example:"[\"a\", \"b\"]"`
+		Scores   map[string]int `json:"scores" example:"{\"math\": 20}"`
+		Inner    *Inner         `json:"inner" example:"{\"name\": \"inner\"}"`
+		Birthday time.Time      `json:"birthday" example:"1980-01-01T00:00:00Z"`
+	}
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[WithExamples](), PublicNameKey: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.EqualJSON(t, result.(schema.Object).Properties, `{
+		"age": {"type": "number", "format": "int32", "example": 42},
+		"ratio": {"type": "number", "format": "float", "example": 0.5},
+		"active": {"type": "boolean", "example": true},
+		"name": {"type": "string", "example": "John, Jr."},
+		"tags": {"type": "array", "items": {"type": "string"}, "example": ["a", "b"]},
+		"scores": {"type": "object", "additionalProperties": {"type": "number", "format": "int32"}, "example": {"math": 20}},
+		"inner": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}, "example": {"name": "inner"}},
+		"birthday": {"type": "string", "format": "date-time", "example": "1980-01-01T00:00:00Z"}
+	}`)
+}
+
+type UUID [16]byte
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(u[:])), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(strings.ReplaceAll(string(text), "-", ""))
+	if err != nil {
+		return err
+	}
+	if len(decoded) != len(u) {
+		return fmt.Errorf("expected %d bytes, got %d", len(u), len(decoded))
+	}
+	copy(u[:], decoded)
+	return nil
+}
+
+// Check that examples of types serialized as strings are parsed as strings.
+func TestStringExamples(t *testing.T) {
+	type WithExamples struct {
+		Id      UUID   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+		Address net.IP `json:"address" example:"127.0.0.1"`
+		Data    []byte `json:"data" example:"aGVsbG8="`
+	}
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[WithExamples](), PublicNameKey: "json"})
+	assert.NilError(t, err)
+	properties := result.(schema.Object).Properties
+	for name, expected := range map[string]string{
+		"id":      "123e4567-e89b-12d3-a456-426614174000",
+		"address": "127.0.0.1",
+		"data":    "aGVsbG8=",
+	} {
+		serialized, err := json.Marshal(properties[name])
+		assert.NilError(t, err)
+		var parsed struct {
+			Example any `json:"example"`
+		}
+		assert.NilError(t, json.Unmarshal(serialized, &parsed))
+		assert.Equal(t, parsed.Example, any(expected), name)
+	}
+
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[struct {
+			Id UUID `json:"id" example:"not a uuid"`
+		}](),
+		reflect.TypeFor[struct {
+			Data []byte `json:"data" example:"not base64!"`
+		}](),
+	} {
+		_, err := schema.FromImplementation(schema.Implementation{Type: typ, PublicNameKey: "json"})
+		assert.Assert(t, err != nil, typ.String())
+	}
+}
+
+// Check that examples and defaults that do not fit the type of the field are rejected.
+func TestTypedExamplesMismatch(t *testing.T) {
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[struct {
+			Age int8 `json:"age" example:"300"`
+		}](),
+		reflect.TypeFor[struct {
+			Active bool `json:"active" example:"yes"`
+		}](),
+		reflect.TypeFor[struct {
+			Tags []int `json:"tags" example:"[\"a\"]"`
+		}](),
+		reflect.TypeFor[struct {
+			Birthday time.Time `json:"birthday" example:"yesterday"`
+		}](),
+		reflect.TypeFor[struct {
+			Inner struct {
+				Name string `json:"name"`
+			} `json:"inner" example:"{\"nom\": \"inner\"}"`
+		}](),
+		reflect.TypeFor[struct {
+			Count uint `json:"count" default:"-1"`
+		}](),
+	} {
+		_, err := schema.FromImplementation(schema.Implementation{Type: typ, PublicNameKey: "json"})
+		if err == nil {
+			t.Errorf("expected an error for %s", typ.String())
+		}
+	}
+}
+
+// Check that defaults are emitted, with tags taking precedence over HasDefault.
+type Color string
+
+func (Color) Default() shared.Json {
+	return "red"
+}
+
+var _ schema.HasDefault = Color("")
+
+func TestDefaults(t *testing.T) {
+	type WithDefaults struct {
+		Count   int      `json:"count" default:"10"`
+		Color   Color    `json:"color"`
+		Other   Color    `json:"other" default:"blue"`
+		Numbers []uint16 `json:"numbers" default:"[1, 2]"`
+	}
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[WithDefaults](), PublicNameKey: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.EqualJSON(t, result.(schema.Object).Properties, `{
+		"count": {"type": "number", "format": "int32", "default": 10},
+		"color": {"type": "string", "default": "red"},
+		"other": {"type": "string", "default": "blue"},
+		"numbers": {"type": "array", "items": {"type": "number", "format": "int64"}, "default": [1, 2]}
+	}`)
+}
+
+// Check that field documentation is emitted, with tags taking precedence over HasDescription.
+type Temperature float64
+
+func (Temperature) Description() string {
+	return "A temperature, in Celsius"
+}
+
+func TestFieldDocumentation(t *testing.T) {
+	type Documented struct {
+		Id       string      `json:"id" description:"The id, generated by the server" readOnly:""`
+		Password string      `json:"password" writeOnly:""`
+		Legacy   int         `json:"legacy" title:"Legacy" deprecated:""`
+		Inside   Temperature `json:"inside"`
+		Outside  Temperature `json:"outside" description:"The temperature outside"`
+		Tags     []string    `json:"tags" description:"Some tags"`
+		Current  int         `json:"current" deprecated:"false" readOnly:"true"`
+	}
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[Documented](), PublicNameKey: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.EqualJSON(t, result.(schema.Object).Properties, `{
+		"id": {"type": "string", "description": "The id, generated by the server", "readOnly": true},
+		"password": {"type": "string", "writeOnly": true},
+		"legacy": {"type": "number", "format": "int32", "title": "Legacy", "deprecated": true},
+		"inside": {"type": "number", "format": "double", "description": "A temperature, in Celsius"},
+		"outside": {"type": "number", "format": "double", "description": "The temperature outside"},
+		"tags": {"type": "array", "items": {"type": "string"}, "description": "Some tags"},
+		"current": {"type": "number", "format": "int32", "readOnly": true}
+	}`)
+
+	_, err = schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[struct {
+		Secret string `json:"secret" readOnly:"" writeOnly:""`
+	}](), PublicNameKey: "json"})
+	assert.ErrorContains(t, err, "mutually exclusive")
+
+	_, err = schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[struct {
+		Legacy string `json:"legacy" deprecated:"maybe"`
+	}](), PublicNameKey: "json"})
+	assert.ErrorContains(t, err, "invalid tag deprecated")
+}
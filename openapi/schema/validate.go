@@ -0,0 +1,429 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// A violation of a schema by a value.
+type ValidationError struct {
+	// A JSON pointer to the offending value, e.g. `/user/name`.
+	//
+	// Empty if the offending value is the root value.
+	Pointer string `json:"pointer"`
+
+	// A human-readable description of the violation.
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Pointer == "" {
+		return e.Message
+	}
+	return fmt.Sprint(e.Pointer, ": ", e.Message)
+}
+
+// Check a JSON value against a schema.
+//
+// `value` may be either
+//   - a value as decoded by `encoding/json` into an `any`, i.e. `nil`, `bool`,
+//     `float64`, `string`, `[]any` or `map[string]any`;
+//   - raw JSON, as a `json.RawMessage`;
+//   - any other Go value, which is first converted to JSON with `encoding/json`.
+//
+// Returns the list of violations, empty if the value conforms to the schema.
+//
+// The schema MUST NOT contain references, see `ValidateWithDefinitions`.
+func Validate(s Schema, value any) []ValidationError {
+	return ValidateWithDefinitions(s, value, nil)
+}
+
+// Check a JSON value against a schema that may contain references
+// to `#/components/schemas/<name>`.
+//
+// `definitions` is the content of `components.schemas`, typically
+// `openapi.Spec.Components.Schemas`.
+func ValidateWithDefinitions(s Schema, value any, definitions map[string]Schema) []ValidationError {
+	normalized, err := normalize(value)
+	if err != nil {
+		return []ValidationError{{
+			Message: fmt.Sprintf("cannot convert value to JSON: %s", err),
+		}}
+	}
+	v := validator{
+		definitions: definitions,
+	}
+	v.validate(s, normalized, "")
+	return v.errors
+}
+
+// Convert a value to the representation of JSON used by `encoding/json`.
+func normalize(value any) (any, error) {
+	switch typed := value.(type) {
+	case nil, bool, float64, string, []any, map[string]any:
+		return value, nil
+	case json.RawMessage:
+		var result any
+		if err := json.Unmarshal(typed, &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		var result any
+		if err := json.Unmarshal(encoded, &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+type validator struct {
+	definitions map[string]Schema
+	errors      []ValidationError
+
+	// The references currently being resolved, to avoid infinite loops.
+	resolving []string
+}
+
+func (v *validator) fail(pointer string, format string, args ...any) {
+	v.errors = append(v.errors, ValidationError{
+		Pointer: pointer,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Run a sub-validation, e.g. for a variant of `oneOf`, without
+// recording errors.
+func (v *validator) try(s Schema, value any, pointer string) []ValidationError {
+	sub := validator{
+		definitions: v.definitions,
+		resolving:   v.resolving,
+	}
+	sub.validate(s, value, pointer)
+	return sub.errors
+}
+
+// Extend a JSON pointer with a segment.
+func appendPointer(pointer string, segment string) string {
+	return fmt.Sprint(pointer, "/", strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+}
+
+// A human-readable name for the JSON type of a value.
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return string(TypeBool)
+	case float64:
+		return string(TypeNumber)
+	case string:
+		return string(TypeString)
+	case []any:
+		return string(TypeArray)
+	case map[string]any:
+		return string(TypeObject)
+	default:
+		return fmt.Sprintf("unsupported %T", value)
+	}
+}
+
+const componentsPrefix = "#/components/schemas/"
+
+func (v *validator) validate(s Schema, value any, pointer string) {
+	switch typed := s.(type) {
+	case nil:
+		// No constraint.
+	case Boolean:
+		if !typed {
+			v.fail(pointer, "no value is allowed here")
+		}
+	case Reference:
+		name, ok := strings.CutPrefix(typed.Ref, componentsPrefix)
+		if !ok {
+			v.fail(pointer, "cannot resolve reference \"%s\", expected a reference to %s", typed.Ref, componentsPrefix)
+			return
+		}
+		resolved, ok := v.definitions[name]
+		if !ok {
+			v.fail(pointer, "cannot resolve reference \"%s\", no such schema", typed.Ref)
+			return
+		}
+		// A reference may only loop back to itself on a smaller value, so
+		// looping on the same pointer means that the schema is ill-formed.
+		key := fmt.Sprint(typed.Ref, "@", pointer)
+		if slices.Contains(v.resolving, key) {
+			v.fail(pointer, "cyclic reference \"%s\"", typed.Ref)
+			return
+		}
+		v.resolving = append(v.resolving, key)
+		v.validate(resolved, value, pointer)
+		v.resolving = v.resolving[:len(v.resolving)-1]
+	case Primitive:
+		v.validateShared(typed.Shared, value, pointer)
+	case Array:
+		if !v.validateShared(typed.Shared, value, pointer) {
+			return
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			v.validate(typed.Items, item, appendPointer(pointer, fmt.Sprint(i)))
+		}
+	case Object:
+		if !v.validateShared(typed.Shared, value, pointer) {
+			return
+		}
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		for _, name := range typed.Required {
+			if _, ok := object[name]; !ok {
+				v.fail(pointer, "missing required property \"%s\"", name)
+			}
+		}
+		// Iterate in a stable order, to make errors reproducible.
+		keys := make([]string, 0, len(object))
+		for k := range object {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if property, ok := typed.Properties[k]; ok {
+				v.validate(property, object[k], appendPointer(pointer, k))
+			} else if typed.AdditionalProperties != nil {
+				if forbidden, ok := (*typed.AdditionalProperties).(Boolean); ok && !bool(forbidden) {
+					v.fail(pointer, "unexpected property \"%s\"", k)
+					continue
+				}
+				v.validate(*typed.AdditionalProperties, object[k], appendPointer(pointer, k))
+			}
+		}
+	case OneOf:
+		var matching []int
+		var closest []ValidationError
+		for i, variant := range typed.OneOf {
+			errors := v.try(variant, value, pointer)
+			if len(errors) == 0 {
+				matching = append(matching, i)
+			} else if closest == nil || len(errors) < len(closest) {
+				closest = errors
+			}
+		}
+		switch len(matching) {
+		case 1:
+			// Success.
+		case 0:
+			if len(typed.OneOf) == 0 {
+				v.fail(pointer, "no variant of oneOf to match")
+				return
+			}
+			v.fail(pointer, "expected exactly one variant of oneOf to match, none did, closest match failed with: %s", closest[0].Error())
+		default:
+			v.fail(pointer, "expected exactly one variant of oneOf to match, variants %v all matched", matching)
+		}
+	case AllOf:
+		for _, variant := range typed.AllOf {
+			v.validate(variant, value, pointer)
+		}
+	default:
+		v.fail(pointer, "cannot validate against schema of type %T", s)
+	}
+}
+
+// Check the constraints of `Shared`.
+//
+// Returns `false` if the value does not have the expected type, in which case
+// the caller should not attempt to look further.
+func (v *validator) validateShared(share Shared, value any, pointer string) bool {
+	if share.Type != "" && jsonTypeOf(value) != string(share.Type) {
+		v.fail(pointer, "expected %s, got %s", share.Type, jsonTypeOf(value))
+		return false
+	}
+	switch typed := value.(type) {
+	case float64:
+		v.validateNumber(share, typed, pointer)
+	case string:
+		v.validateString(share, typed, pointer)
+	case []any:
+		if share.MinItems != nil && int64(len(typed)) < *share.MinItems {
+			v.fail(pointer, "expected at least %d items, got %d", *share.MinItems, len(typed))
+		}
+		if share.MaxItems != nil && int64(len(typed)) > *share.MaxItems {
+			v.fail(pointer, "expected at most %d items, got %d", *share.MaxItems, len(typed))
+		}
+	case map[string]any:
+		if share.MinProperties != nil && int64(len(typed)) < *share.MinProperties {
+			v.fail(pointer, "expected at least %d properties, got %d", *share.MinProperties, len(typed))
+		}
+		if share.MaxProperties != nil && int64(len(typed)) > *share.MaxProperties {
+			v.fail(pointer, "expected at most %d properties, got %d", *share.MaxProperties, len(typed))
+		}
+	}
+	if share.Enum != nil {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			v.fail(pointer, "cannot compare value to enum: %s", err)
+			return true
+		}
+		found := false
+		for _, candidate := range *share.Enum {
+			// Enum values may be Go values, e.g. typed constants, so we compare their JSON encodings.
+			encodedCandidate, err := json.Marshal(candidate)
+			if err == nil && string(encodedCandidate) == string(encoded) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(pointer, "value %s is not one of the enumerated values", string(encoded))
+		}
+	}
+	return true
+}
+
+func (v *validator) validateNumber(share Shared, number float64, pointer string) {
+	if share.Format != nil {
+		switch Format(*share.Format) {
+		case FormatInt32:
+			if number != math.Trunc(number) || number < math.MinInt32 || number > math.MaxInt32 {
+				v.fail(pointer, "expected a 32-bit integer, got %v", number)
+			}
+		case FormatInt64:
+			if number != math.Trunc(number) || number < math.MinInt64 || number > math.MaxInt64 {
+				v.fail(pointer, "expected a 64-bit integer, got %v", number)
+			}
+		}
+	}
+	if share.Minimum != nil && number < *share.Minimum {
+		v.fail(pointer, "expected a value >= %v, got %v", *share.Minimum, number)
+	}
+	if share.Maximum != nil && number > *share.Maximum {
+		v.fail(pointer, "expected a value <= %v, got %v", *share.Maximum, number)
+	}
+	if share.ExclusiveMinimum != nil && number <= *share.ExclusiveMinimum {
+		v.fail(pointer, "expected a value > %v, got %v", *share.ExclusiveMinimum, number)
+	}
+	if share.ExclusiveMaximum != nil && number >= *share.ExclusiveMaximum {
+		v.fail(pointer, "expected a value < %v, got %v", *share.ExclusiveMaximum, number)
+	}
+	if share.MultipleOf != nil && *share.MultipleOf > 0 {
+		quotient := number / *share.MultipleOf
+		// Tolerate rounding errors, e.g. 0.3 / 0.1.
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(pointer, "expected a multiple of %v, got %v", *share.MultipleOf, number)
+		}
+	}
+}
+
+var (
+	hostnameRegex = regexp.MustCompile(`^(?i:[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)(\.(?i:[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?))*$`)
+	timeRegex     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(?i:z|[+-]\d{2}:\d{2})$`)
+	durationRegex = regexp.MustCompile(`^P(\d+W|(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?)$`)
+)
+
+// The patterns of schemas, compiled once.
+var patterns sync.Map // map[string]compiledPattern
+
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// Compile the pattern of a schema, reusing the result of previous calls.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := patterns.Load(pattern); ok {
+		compiled := cached.(compiledPattern)
+		return compiled.re, compiled.err
+	}
+	re, err := regexp.Compile(pattern)
+	patterns.Store(pattern, compiledPattern{re: re, err: err})
+	return re, err
+}
+
+// Check that a string matches a well-known format.
+//
+// Formats we do not know are considered annotations and accepted.
+func checkFormat(format Format, str string) error {
+	switch format {
+	case FormatDateTime:
+		if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+			return fmt.Errorf("expected an RFC 3339 date-time")
+		}
+	case FormatDate:
+		if _, err := time.Parse(time.DateOnly, str); err != nil {
+			return fmt.Errorf("expected an RFC 3339 full-date")
+		}
+	case FormatTime:
+		if !timeRegex.MatchString(str) {
+			return fmt.Errorf("expected an RFC 3339 full-time")
+		}
+	case FormatDuration:
+		if !durationRegex.MatchString(str) || str == "P" || strings.HasSuffix(str, "T") {
+			return fmt.Errorf("expected an ISO 8601 duration")
+		}
+	case FormatEmail, FormatIdnEmail:
+		address, err := mail.ParseAddress(str)
+		if err != nil || address.Address != str {
+			return fmt.Errorf("expected an email address")
+		}
+	case FormatHostname, FormatIdnHostname:
+		if len(str) > 253 || !hostnameRegex.MatchString(str) {
+			return fmt.Errorf("expected a hostname")
+		}
+	case FormatUri:
+		parsed, err := url.Parse(str)
+		if err != nil || !parsed.IsAbs() {
+			return fmt.Errorf("expected an absolute URI")
+		}
+	case FormatRegex:
+		if _, err := regexp.Compile(str); err != nil {
+			return fmt.Errorf("expected a regular expression")
+		}
+	case FormatByte:
+		if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+			return fmt.Errorf("expected base64-encoded data")
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateString(share Shared, str string, pointer string) {
+	length := int64(utf8.RuneCountInString(str))
+	if share.MinLength != nil && length < *share.MinLength {
+		v.fail(pointer, "expected at least %d characters, got %d", *share.MinLength, length)
+	}
+	if share.MaxLength != nil && length > *share.MaxLength {
+		v.fail(pointer, "expected at most %d characters, got %d", *share.MaxLength, length)
+	}
+	if share.Pattern != nil {
+		re, err := compilePattern(*share.Pattern)
+		if err != nil {
+			v.fail(pointer, "invalid pattern \"%s\" in schema: %s", *share.Pattern, err)
+		} else if !re.MatchString(str) {
+			v.fail(pointer, "value \"%s\" does not match pattern \"%s\"", str, *share.Pattern)
+		}
+	}
+	if share.Format != nil {
+		if err := checkFormat(Format(*share.Format), str); err != nil {
+			v.fail(pointer, "%s, got \"%s\"", err, str)
+		}
+	}
+}
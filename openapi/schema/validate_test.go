@@ -0,0 +1,129 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pasqal-io/gousset/openapi/schema"
+	"github.com/pasqal-io/gousset/shared"
+	"gotest.tools/assert"
+)
+
+type Address struct {
+	Street string `json:"street" minLength:"1"`
+	Zip    string `json:"zip" pattern:"^[0-9]{5}$"`
+}
+
+type Person struct {
+	Name     string    `json:"name" minLength:"1" maxLength:"10"`
+	Email    string    `json:"email" format:"email"`
+	Age      int       `json:"age" minimum:"0" exclusiveMaximum:"150"`
+	Score    float64   `json:"score" multipleOf:"0.5"`
+	Tags     []string  `json:"tags" maxItems:"2"`
+	Birthday time.Time `json:"birthday"`
+	Address  Address   `json:"address"`
+}
+
+func personSchema(t *testing.T) schema.Schema {
+	result, err := schema.FromImplementation(schema.Implementation{Type: reflect.TypeFor[Person](), PublicNameKey: "json"})
+	assert.NilError(t, err)
+	return result
+}
+
+// A conforming value, provided either as a Go value or as raw JSON, passes validation.
+func TestValidateValid(t *testing.T) {
+	s := personSchema(t)
+	person := Person{
+		Name:     "John",
+		Email:    "john@example.org",
+		Age:      42,
+		Score:    3.5,
+		Tags:     []string{"a"},
+		Birthday: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+		Address:  Address{Street: "Main St", Zip: "12345"},
+	}
+	assert.DeepEqual(t, schema.Validate(s, person), []schema.ValidationError(nil))
+
+	raw, err := json.Marshal(person)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, schema.Validate(s, json.RawMessage(raw)), []schema.ValidationError(nil))
+}
+
+// Each violation is reported with a JSON pointer to the offending value.
+func TestValidateInvalid(t *testing.T) {
+	s := personSchema(t)
+	errors := schema.Validate(s, json.RawMessage(`{
+		"name": "",
+		"email": "John <john@example.org>",
+		"age": 150,
+		"score": 3.2,
+		"tags": ["a", "b", 3],
+		"birthday": "yesterday",
+		"address": {"zip": "1234"}
+	}`))
+	assert.DeepEqual(t, errors, []schema.ValidationError{
+		{Pointer: "/address", Message: "missing required property \"street\""},
+		{Pointer: "/address/zip", Message: "value \"1234\" does not match pattern \"^[0-9]{5}$\""},
+		{Pointer: "/age", Message: "expected a value < 150, got 150"},
+		{Pointer: "/birthday", Message: "expected an RFC 3339 date-time, got \"yesterday\""},
+		{Pointer: "/email", Message: "expected an email address, got \"John <john@example.org>\""},
+		{Pointer: "/name", Message: "expected at least 1 characters, got 0"},
+		{Pointer: "/score", Message: "expected a multiple of 0.5, got 3.2"},
+		{Pointer: "/tags", Message: "expected at most 2 items, got 3"},
+		{Pointer: "/tags/2", Message: "expected string, got number"},
+	})
+}
+
+// `additionalProperties: false` forbids unknown properties.
+func TestValidateAdditionalProperties(t *testing.T) {
+	s := schema.Object{
+		Shared:               schema.Shared{Type: schema.TypeObject},
+		Properties:           map[string]schema.Schema{"a": schema.Primitive{Shared: schema.Shared{Type: schema.TypeBool}}},
+		AdditionalProperties: shared.Ptr[schema.Schema](schema.False),
+	}
+	assert.DeepEqual(t, schema.Validate(s, map[string]any{"a": true, "b~/": 1.0}), []schema.ValidationError{
+		{Pointer: "", Message: "unexpected property \"b~/\""},
+	})
+}
+
+// `oneOf` requires exactly one match, `allOf` requires all.
+func TestValidateCombinators(t *testing.T) {
+	number := schema.Primitive{Shared: schema.Shared{Type: schema.TypeNumber}}
+	positive := schema.Primitive{Shared: schema.Shared{Type: schema.TypeNumber, Minimum: shared.Ptr(0.0)}}
+	str := schema.Primitive{Shared: schema.Shared{Type: schema.TypeString}}
+
+	assert.Equal(t, len(schema.Validate(schema.OneOf{OneOf: []schema.Schema{number, str}}, "foo")), 0)
+	assert.DeepEqual(t, schema.Validate(schema.OneOf{OneOf: []schema.Schema{number, positive}}, 1.0), []schema.ValidationError{
+		{Message: "expected exactly one variant of oneOf to match, variants [0 1] all matched"},
+	})
+	assert.DeepEqual(t, schema.Validate(schema.OneOf{OneOf: []schema.Schema{number, str}}, true), []schema.ValidationError{
+		{Message: "expected exactly one variant of oneOf to match, none did, closest match failed with: expected number, got boolean"},
+	})
+	assert.DeepEqual(t, schema.Validate(schema.AllOf{AllOf: []schema.Schema{number, positive}}, -1.0), []schema.ValidationError{
+		{Message: "expected a value >= 0, got -1"},
+	})
+}
+
+// References are resolved against definitions.
+func TestValidateReferences(t *testing.T) {
+	// A linked list.
+	definitions := map[string]schema.Schema{
+		"List": schema.Object{
+			Shared:   schema.Shared{Type: schema.TypeObject},
+			Required: []string{"value"},
+			Properties: map[string]schema.Schema{
+				"value": schema.Primitive{Shared: schema.Shared{Type: schema.TypeNumber}},
+				"next":  schema.Ref("#/components/schemas/List"),
+			},
+		},
+	}
+	value := json.RawMessage(`{"value": 1, "next": {"value": 2, "next": {"value": "three"}}}`)
+	assert.DeepEqual(t, schema.ValidateWithDefinitions(schema.Ref("#/components/schemas/List"), value, definitions), []schema.ValidationError{
+		{Pointer: "/next/next/value", Message: "expected number, got string"},
+	})
+	assert.DeepEqual(t, schema.Validate(schema.Ref("#/components/schemas/List"), value), []schema.ValidationError{
+		{Message: "cannot resolve reference \"#/components/schemas/List\", no such schema"},
+	})
+}
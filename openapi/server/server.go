@@ -0,0 +1,26 @@
+// Servers hosting the API.
+package server
+
+// https://spec.openapis.org/oas/v3.0.1.html#server-object
+type Server struct {
+	// A URL to the target host. This URL supports Server Variables and MAY be relative, to indicate that the host location is relative to the location where the OpenAPI document is being served. Variable substitutions will be made when a variable is named in {brackets}.
+	Url string `json:"url"`
+
+	// An optional string describing the host designated by the URL. May include Markdown.
+	Description *string `json:"description,omitempty"`
+
+	// A map between a variable name and its value. The value is used for substitution in the server’s URL template.
+	Variables *map[string]Variable `json:"variables,omitempty"`
+}
+
+// https://spec.openapis.org/oas/v3.0.1.html#server-variable-object
+type Variable struct {
+	// An enumeration of string values to be used if the substitution options are from a limited set. The array SHOULD NOT be empty.
+	Enum *[]string `json:"enum,omitempty"`
+
+	// The default value to use for substitution, which SHALL be sent if an alternate value is not supplied.
+	Default string `json:"default"`
+
+	// An optional description for the server variable. May include Markdown.
+	Description *string `json:"description,omitempty"`
+}
@@ -0,0 +1,180 @@
+// Register handlers on a `net/http.ServeMux` and document them at the same time.
+//
+// Patterns use the syntax of `http.ServeMux`, e.g. `GET /users/{id}`, so that
+// the spec is built from the same declarations that route traffic:
+//
+//	mux := servemux.New(nil)
+//	err := mux.Handle("GET /users/{id}", getUser, path.VerbImplementation{...})
+//	...
+//	spec, err := openapi.FromImplementation(openapi.Implementation{
+//		Endpoints: mux.Endpoints(),
+//		...
+//	})
+package servemux
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pasqal-io/gousset/openapi/path"
+)
+
+// The verbs that may be documented, indexed by HTTP method.
+var verbs = map[string]path.Verb{
+	http.MethodGet:     path.Get,
+	http.MethodPut:     path.Put,
+	http.MethodPost:    path.Post,
+	http.MethodDelete:  path.Delete,
+	http.MethodOptions: path.Options,
+	http.MethodPatch:   path.Patch,
+	http.MethodHead:    path.Head,
+	http.MethodTrace:   path.Trace,
+}
+
+// A `http.ServeMux` recording the documentation of its handlers.
+type Mux struct {
+	mux *http.ServeMux
+
+	// The endpoints, in the order in which their path was first registered.
+	endpoints []path.Implementation
+
+	// The index of each path in `endpoints`.
+	index map[string]int
+}
+
+// Wrap a `http.ServeMux`.
+//
+// If `mux` is nil, create a new one.
+func New(mux *http.ServeMux) *Mux {
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
+	return &Mux{
+		mux:       mux,
+		endpoints: nil,
+		index:     make(map[string]int),
+	}
+}
+
+// The underlying `http.ServeMux`.
+//
+// Use it to register handlers that should not appear in the spec.
+func (m *Mux) ServeMux() *http.ServeMux {
+	return m.mux
+}
+
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// Register and document a handler.
+//
+// The pattern MUST specify a method, e.g. `GET /users/{id}`. Wildcards
+// `{name}` and `{name...}` become path parameter `name`, which should
+// be declared in `impl.Input`. As `http.ServeMux`, a `GET` pattern also
+// serves `HEAD` requests, but only `GET` is documented.
+//
+// Wildcards `{name...}` are converted by `openapi.FromImplementation`,
+// which requires `openapi.Implementation.Routes` to accept `path.CaptureBraces`,
+// as it does by default.
+//
+// As `http.ServeMux.Handle`, panics if the pattern conflicts with a
+// pattern already registered.
+func (m *Mux) Handle(pattern string, handler http.Handler, impl path.VerbImplementation) error {
+	verb, route, err := ParsePattern(pattern)
+	if err != nil {
+		return err
+	}
+	i, ok := m.index[route]
+	if ok {
+		if _, ok := m.endpoints[i].PerVerb[verb]; ok {
+			return fmt.Errorf("invalid pattern \"%s\": verb %s is already documented for %s", pattern, verb, route)
+		}
+	}
+	m.mux.Handle(pattern, handler)
+	if !ok {
+		i = len(m.endpoints)
+		m.index[route] = i
+		m.endpoints = append(m.endpoints, path.Implementation{
+			Summary:     "",
+			Description: nil,
+			Path:        route,
+			PerVerb:     make(map[path.Verb]path.VerbImplementation),
+		})
+	}
+	m.endpoints[i].PerVerb[verb] = impl
+	return nil
+}
+
+// Register and document a handler function.
+//
+// See `Handle`.
+func (m *Mux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), impl path.VerbImplementation) error {
+	return m.Handle(pattern, http.HandlerFunc(handler), impl)
+}
+
+// The endpoints registered so far, for `openapi.Implementation.Endpoints`.
+func (m *Mux) Endpoints() []path.Implementation {
+	result := make([]path.Implementation, len(m.endpoints))
+	copy(result, m.endpoints)
+	return result
+}
+
+// Convert a `http.ServeMux` pattern, e.g. `GET example.org/users/{id}/{rest...}`,
+// into a verb and a path, e.g. `path.Get` and `/users/{id}/{rest...}`.
+//
+// The host, if any, is dropped, as is the `{$}` that marks an exact match.
+// Wildcards `{name...}` are kept, so that the spec records that they match
+// the rest of the path.
+func ParsePattern(pattern string) (path.Verb, string, error) {
+	method, rest, found := strings.Cut(pattern, " ")
+	if !found {
+		method, rest, found = strings.Cut(pattern, "\t")
+	}
+	if !found {
+		return "", "", fmt.Errorf("invalid pattern \"%s\": expected a method, e.g. \"GET %s\"", pattern, pattern)
+	}
+	verb, ok := verbs[method]
+	if !ok {
+		return "", "", fmt.Errorf("invalid pattern \"%s\": method %s cannot be documented", pattern, method)
+	}
+	rest = strings.TrimLeft(rest, " \t")
+	slash := strings.IndexByte(rest, '/')
+	if slash == -1 {
+		return "", "", fmt.Errorf("invalid pattern \"%s\": expected a path, starting with '/'", pattern)
+	}
+	segments := strings.Split(rest[slash:], "/")
+	for i, segment := range segments {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+		name, ok := strings.CutPrefix(segment, "{")
+		if ok {
+			name, ok = strings.CutSuffix(name, "}")
+		}
+		if !ok {
+			return "", "", fmt.Errorf("invalid pattern \"%s\": a wildcard must be a full path segment, got \"%s\"", pattern, segment)
+		}
+		last := i == len(segments)-1
+		if name == "$" {
+			if !last {
+				return "", "", fmt.Errorf("invalid pattern \"%s\": {$} must be at the end", pattern)
+			}
+			segments[i] = ""
+			continue
+		}
+		name, multi := strings.CutSuffix(name, "...")
+		if multi && !last {
+			return "", "", fmt.Errorf("invalid pattern \"%s\": {%s...} must be at the end", pattern, name)
+		}
+		if name == "" {
+			return "", "", fmt.Errorf("invalid pattern \"%s\": empty wildcard", pattern)
+		}
+		if multi {
+			name += "..."
+		}
+		segments[i] = fmt.Sprint("{", name, "}")
+	}
+	return verb, strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,199 @@
+package servemux_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pasqal-io/gousset/middleware"
+	"github.com/pasqal-io/gousset/openapi"
+	"github.com/pasqal-io/gousset/openapi/path"
+	"github.com/pasqal-io/gousset/servemux"
+	"github.com/pasqal-io/gousset/shared/structs"
+	"github.com/pasqal-io/gousset/testutils"
+	"gotest.tools/assert"
+)
+
+func TestParsePattern(t *testing.T) {
+	for pattern, expected := range map[string]struct {
+		verb path.Verb
+		path string
+	}{
+		"GET /":                       {path.Get, "/"},
+		"GET /{$}":                    {path.Get, "/"},
+		"POST /users/":                {path.Post, "/users/"},
+		"DELETE /users/{id}":          {path.Delete, "/users/{id}"},
+		"PATCH  example.org/a/{b}/c":  {path.Patch, "/a/{b}/c"},
+		"PUT\t/files/{path...}":       {path.Put, "/files/{path...}"},
+		"HEAD /users":                 {path.Head, "/users"},
+		"OPTIONS /users/{userId}/{$}": {path.Options, "/users/{userId}/"},
+	} {
+		verb, route, err := servemux.ParsePattern(pattern)
+		assert.NilError(t, err, pattern)
+		assert.Equal(t, verb, expected.verb, pattern)
+		assert.Equal(t, route, expected.path, pattern)
+	}
+	for pattern, message := range map[string]string{
+		"/users":                   "expected a method",
+		"CONNECT /users":           "method CONNECT cannot be documented",
+		"GET users":                "expected a path",
+		"GET /users/id{id}":        "must be a full path segment",
+		"GET /files/{path...}/raw": "{path...} must be at the end",
+		"GET /{$}/users":           "{$} must be at the end",
+		"GET /users/{}":            "empty wildcard",
+	} {
+		_, _, err := servemux.ParsePattern(pattern)
+		assert.ErrorContains(t, err, message, pattern)
+	}
+}
+
+type UserPath struct {
+	Id string `path:"id" description:"The id of the user"`
+}
+
+type UserBody struct {
+	Name string `json:"name"`
+}
+
+func TestMux(t *testing.T) {
+	mux := servemux.New(nil)
+	assert.NilError(t, mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "get "+r.PathValue("id"))
+	}, path.VerbImplementation{
+		Input:   reflect.TypeFor[structs.Path[UserPath]](),
+		Summary: "Fetch a user",
+	}))
+	assert.NilError(t, mux.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "put "+r.PathValue("id"))
+	}, path.VerbImplementation{
+		Input:   reflect.TypeFor[structs.BodyPath[UserBody, UserPath]](),
+		Summary: "Update a user",
+	}))
+	assert.NilError(t, mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {}, path.VerbImplementation{
+		Summary: "Check that the service is up",
+	}))
+	err := mux.HandleFunc("GET example.org/users/{id}", func(w http.ResponseWriter, r *http.Request) {}, path.VerbImplementation{})
+	assert.ErrorContains(t, err, "verb get is already documented for /users/{id}")
+
+	// Handlers that should not be documented.
+	mux.ServeMux().HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {})
+
+	// Requests are routed.
+	for request, expected := range map[*http.Request]string{
+		httptest.NewRequest(http.MethodGet, "/users/alice", nil): "get alice",
+		httptest.NewRequest(http.MethodPut, "/users/bob", nil):   "put bob",
+	} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		assert.Equal(t, recorder.Body.String(), expected)
+	}
+
+	// Endpoints are documented.
+	endpoints := mux.Endpoints()
+	assert.Equal(t, len(endpoints), 2)
+	assert.Equal(t, endpoints[0].Path, "/users/{id}")
+	assert.Equal(t, endpoints[1].Path, "/health")
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Info: openapi.Info{
+			Title:   "Users",
+			Version: "1.0",
+		},
+		Endpoints: endpoints,
+	})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Paths, `{
+		"/health": {
+			"get": {
+				"summary": "Check that the service is up",
+				"operationId": "get /health",
+				"responses": {"default": {"description": ""}}
+			}
+		},
+		"/users/{id}": {
+			"parameters": [
+				{
+					"name": "id",
+					"in": "path",
+					"description": "The id of the user",
+					"required": true,
+					"schema": {"type": "string"}
+				}
+			],
+			"get": {
+				"summary": "Fetch a user",
+				"operationId": "get /users/{id}",
+				"responses": {"default": {"description": ""}}
+			},
+			"put": {
+				"summary": "Update a user",
+				"operationId": "put /users/{id}",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"type": "object",
+								"properties": {
+									"name": {"type": "string"}
+								},
+								"required": ["name"]
+							}
+						}
+					}
+				},
+				"responses": {"default": {"description": ""}}
+			}
+		}
+	}`)
+}
+
+type FilePath struct {
+	Rest string `path:"rest" description:"The path of the file"`
+}
+
+// Wildcards `{name...}` match several segments, in the spec and in the middleware.
+func TestMuxRest(t *testing.T) {
+	mux := servemux.New(nil)
+	assert.NilError(t, mux.HandleFunc("GET /files/{rest...}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "get "+r.PathValue("rest"))
+	}, path.VerbImplementation{
+		Input:   reflect.TypeFor[structs.Path[FilePath]](),
+		Summary: "Fetch a file",
+	}))
+	spec, err := openapi.FromImplementation(openapi.Implementation{
+		Info: openapi.Info{
+			Title:   "Files",
+			Version: "1.0",
+		},
+		Endpoints: mux.Endpoints(),
+	})
+	assert.NilError(t, err)
+	testutils.EqualJSON(t, spec.Paths, `{
+		"/files/{rest}": {
+			"get": {
+				"summary": "Fetch a file",
+				"operationId": "get /files/{rest...}",
+				"parameters": [
+					{
+						"name": "rest",
+						"in": "path",
+						"description": "The path of the file",
+						"required": true,
+						"schema": {"type": "string"},
+						"x-rest": true
+					}
+				],
+				"responses": {"default": {"description": ""}}
+			}
+		}
+	}`)
+
+	validator, err := middleware.NewRequestValidator(spec, middleware.Options{RejectUnknownRoutes: true})
+	assert.NilError(t, err)
+	recorder := httptest.NewRecorder()
+	validator.Wrap(mux).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/files/a/b/c", nil))
+	assert.Equal(t, recorder.Code, http.StatusOK)
+	assert.Equal(t, recorder.Body.String(), "get a/b/c")
+}
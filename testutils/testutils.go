@@ -3,9 +3,13 @@ package testutils
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/kinbiko/jsonassert"
+	"github.com/pasqal-io/gousset/middleware"
+	"github.com/pasqal-io/gousset/openapi"
 	"github.com/pasqal-io/gousset/shared"
 	"github.com/pb33f/libopenapi"
 	validator "github.com/pb33f/libopenapi-validator"
@@ -83,3 +87,30 @@ const (
 	BooleanTrue  = Boolean(true)
 	BooleanFalse = Boolean(false)
 )
+
+// Check that a recorded response to `req` conforms to the spec.
+//
+// Reports undeclared status codes and content types, missing required
+// headers and bodies that do not match their schema.
+func ValidateResponse(t *testing.T, spec openapi.Spec, req *http.Request, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	validator, err := middleware.NewResponseValidator(spec, middleware.ResponseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := validator.Validate(req, recorder.Code, recorder.Header(), recorder.Body.Bytes())
+	for _, violation := range violations {
+		t.Errorf("%s %s: response %d does not conform to the spec at %s: %s", req.Method, req.URL.Path, recorder.Code, violation.Pointer, violation.Message)
+	}
+}
+
+// Serve `req` with `handler` and check that the response conforms to the spec.
+//
+// Returns the recorded response for further inspection.
+func ServeAndValidateResponse(t *testing.T, spec openapi.Spec, handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	ValidateResponse(t, spec, req, recorder)
+	return recorder
+}